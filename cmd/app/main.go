@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/vanadium23/kompanion/config"
 	"github.com/vanadium23/kompanion/internal/app"
@@ -16,6 +18,22 @@ func main() {
 		log.Fatalf("Config error: %s", err)
 	}
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			if len(os.Args) != 3 {
+				fmt.Fprintln(os.Stderr, "usage: kompanion import <dir>")
+				os.Exit(2)
+			}
+			app.Import(cfg, os.Args[2])
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+
 	// Run
 	app.Run(cfg)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type (
@@ -16,6 +17,9 @@ type (
 		Log
 		PG
 		BookStorage
+		Inbox
+		Metadata
+		Converter
 	}
 
 	// App -.
@@ -49,8 +53,50 @@ type (
 	}
 
 	BookStorage struct {
-		Type string
-		Path string
+		Type      string
+		Path      string
+		TrashDays int
+		S3        S3
+	}
+
+	// S3 is a bucket of S3-compatible storage.
+	S3 struct {
+		Bucket    string
+		Endpoint  string
+		Region    string
+		AccessKey string
+		SecretKey string
+		PathStyle bool
+	}
+
+	// Inbox -.
+	Inbox struct {
+		Path     string
+		Interval time.Duration
+	}
+
+	// Metadata -.
+	Metadata struct {
+		Providers            []string
+		OpenLibraryURL       string
+		OpenLibraryCoversURL string
+		GoogleBooksURL       string
+		GoogleBooksKey       string
+	}
+
+	// Converter -.
+	Converter struct {
+		Commands  []ConverterCommand
+		Workers   int
+		QueueSize int
+		Timeout   time.Duration
+	}
+
+	// ConverterCommand is an external program converting books to the format.
+	ConverterCommand struct {
+		Format  string
+		From    []string
+		Command string
 	}
 )
 
@@ -81,6 +127,21 @@ func NewConfig(version string) (*Config, error) {
 		return nil, err
 	}
 
+	inbox, err := readInboxConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := readMetadataConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	converter, err := readConverterConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		App: App{
 			Name:    "kompanion",
@@ -91,6 +152,9 @@ func NewConfig(version string) (*Config, error) {
 		Log:         log,
 		PG:          postgres,
 		BookStorage: bookStorage,
+		Inbox:       inbox,
+		Metadata:    metadata,
+		Converter:   converter,
 	}, nil
 }
 
@@ -168,12 +232,159 @@ func readBookStorageConfig() (BookStorage, error) {
 		bstorage_type = "postgres"
 	}
 	bstorage_path := readPrefixedEnv("BSTORAGE_PATH")
+
+	trashDays := 30
+	trashDaysEnv := readPrefixedEnv("BSTORAGE_TRASH_DAYS")
+	if trashDaysEnv != "" {
+		trashDaysEnvInt, err := strconv.Atoi(trashDaysEnv)
+		if err != nil || trashDaysEnvInt <= 0 {
+			return BookStorage{}, fmt.Errorf("trash days is not a positive number")
+		}
+		trashDays = trashDaysEnvInt
+	}
+
+	var s3 S3
+	if bstorage_type == "s3" {
+		s3 = S3{
+			Bucket:    readPrefixedEnv("BSTORAGE_S3_BUCKET"),
+			Endpoint:  readPrefixedEnv("BSTORAGE_S3_ENDPOINT"),
+			Region:    readPrefixedEnv("BSTORAGE_S3_REGION"),
+			AccessKey: readPrefixedEnv("BSTORAGE_S3_ACCESS_KEY"),
+			SecretKey: readPrefixedEnv("BSTORAGE_S3_SECRET_KEY"),
+		}
+		if s3.Bucket == "" {
+			return BookStorage{}, fmt.Errorf("s3 bucket is empty")
+		}
+		pathStyleEnv := readPrefixedEnv("BSTORAGE_S3_PATH_STYLE")
+		if pathStyleEnv != "" {
+			pathStyle, err := strconv.ParseBool(pathStyleEnv)
+			if err != nil {
+				return BookStorage{}, fmt.Errorf("s3 path style is not a boolean")
+			}
+			s3.PathStyle = pathStyle
+		}
+	}
+
 	return BookStorage{
-		Type: bstorage_type,
-		Path: bstorage_path,
+		Type:      bstorage_type,
+		Path:      bstorage_path,
+		TrashDays: trashDays,
+		S3:        s3,
+	}, nil
+}
+
+func readInboxConfig() (Inbox, error) {
+	interval := time.Minute
+	intervalEnv := readPrefixedEnv("INBOX_INTERVAL")
+	if intervalEnv != "" {
+		parsed, err := time.ParseDuration(intervalEnv)
+		if err != nil || parsed <= 0 {
+			return Inbox{}, fmt.Errorf("inbox interval is not a positive duration")
+		}
+		interval = parsed
+	}
+
+	return Inbox{
+		Path:     readPrefixedEnv("INBOX_PATH"),
+		Interval: interval,
 	}, nil
 }
 
+func readMetadataConfig() (Metadata, error) {
+	providersEnv := readPrefixedEnv("METADATA_PROVIDERS")
+	if providersEnv == "" {
+		providersEnv = "openlibrary,googlebooks"
+	}
+
+	var providers []string
+	for _, provider := range strings.Split(providersEnv, ",") {
+		provider = strings.TrimSpace(provider)
+		switch provider {
+		case "", "none":
+		case "openlibrary", "googlebooks":
+			providers = append(providers, provider)
+		default:
+			return Metadata{}, fmt.Errorf("unknown metadata provider: %s", provider)
+		}
+	}
+
+	return Metadata{
+		Providers:            providers,
+		OpenLibraryURL:       readPrefixedEnv("METADATA_OPENLIBRARY_URL"),
+		OpenLibraryCoversURL: readPrefixedEnv("METADATA_OPENLIBRARY_COVERS_URL"),
+		GoogleBooksURL:       readPrefixedEnv("METADATA_GOOGLEBOOKS_URL"),
+		GoogleBooksKey:       readPrefixedEnv("METADATA_GOOGLEBOOKS_KEY"),
+	}, nil
+}
+
+func readConverterConfig() (Converter, error) {
+	var commands []ConverterCommand
+	for _, format := range strings.Split(readPrefixedEnv("CONVERTERS"), ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		key := "CONVERTER_" + strings.ReplaceAll(format, ".", "_")
+		command := readPrefixedEnv(key + "_COMMAND")
+		if command == "" {
+			return Converter{}, fmt.Errorf("command of %s converter is empty", format)
+		}
+		fromEnv := readPrefixedEnv(key + "_FROM")
+		if fromEnv == "" {
+			fromEnv = "epub"
+		}
+		var from []string
+		for _, source := range strings.Split(fromEnv, ",") {
+			if source = strings.ToLower(strings.TrimSpace(source)); source != "" {
+				from = append(from, source)
+			}
+		}
+		commands = append(commands, ConverterCommand{
+			Format:  format,
+			From:    from,
+			Command: command,
+		})
+	}
+
+	workers, err := readPositiveInt("CONVERTER_WORKERS", 1)
+	if err != nil {
+		return Converter{}, err
+	}
+	queueSize, err := readPositiveInt("CONVERTER_QUEUE_SIZE", 100)
+	if err != nil {
+		return Converter{}, err
+	}
+
+	timeout := 5 * time.Minute
+	timeoutEnv := readPrefixedEnv("CONVERTER_TIMEOUT")
+	if timeoutEnv != "" {
+		parsed, err := time.ParseDuration(timeoutEnv)
+		if err != nil || parsed <= 0 {
+			return Converter{}, fmt.Errorf("converter timeout is not a positive duration")
+		}
+		timeout = parsed
+	}
+
+	return Converter{
+		Commands:  commands,
+		Workers:   workers,
+		QueueSize: queueSize,
+		Timeout:   timeout,
+	}, nil
+}
+
+func readPositiveInt(key string, defaultValue int) (int, error) {
+	env := readPrefixedEnv(key)
+	if env == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(env)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s is not a positive number", strings.ToLower(key))
+	}
+	return value, nil
+}
+
 func readPrefixedEnv(key string) string {
 	envKey := fmt.Sprintf("KOMPANION_%s", strings.ToUpper(key))
 	return os.Getenv(envKey)
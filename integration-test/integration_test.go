@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -16,6 +17,8 @@ import (
 	"github.com/Eun/go-hit"
 	. "github.com/Eun/go-hit"
 	petname "github.com/dustinkirkland/golang-petname"
+
+	"github.com/vanadium23/kompanion/pkg/utils"
 )
 
 const (
@@ -340,6 +343,41 @@ func TestHTTPKompanionShelf(t *testing.T) {
 		Expect().Body().Bytes().Equal(bookContent),
 		Expect().Headers("Content-Disposition").Equal("attachment; filename="+filename),
 	)
+
+	// delete book to trash and restore it
+	Test(t,
+		HTTPClient(client),
+		Description("Kompanion Delete Book"),
+		Post(fmt.Sprintf("%s/books/%s/delete", basePath, bookID)),
+		Expect().Status().Equal(http.StatusFound),
+	)
+	Test(t,
+		HTTPClient(client),
+		Description("Kompanion Deleted Book is not Listed"),
+		Get(basePath+"/books/"),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().NotContains(bookID),
+	)
+	Test(t,
+		HTTPClient(client),
+		Description("Kompanion Deleted Book in Trash"),
+		Get(basePath+"/books/trash"),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
+	)
+	Test(t,
+		HTTPClient(client),
+		Description("Kompanion Restore Book"),
+		Post(fmt.Sprintf("%s/books/%s/restore", basePath, bookID)),
+		Expect().Status().Equal(http.StatusFound),
+	)
+	Test(t,
+		HTTPClient(client),
+		Description("Kompanion Restored Book is Listed"),
+		Get(fmt.Sprintf("%s/books/%s", basePath, bookID)),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
+	)
 }
 
 // stats
@@ -449,6 +487,43 @@ func TestHTTPKompanionOPDS(t *testing.T) {
 		Send().Headers("Authorization").Add(basicAuth),
 		Expect().Status().Equal(http.StatusOK),
 		Expect().Body().String().Contains("/opds/newest"),
+		Expect().Body().String().Contains(`xmlns:dc="http://purl.org/dc/terms/"`),
+		Expect().Body().String().Contains("<dc:language>en</dc:language>"),
+		Expect().Body().String().Contains("urn:uuid:"+bookID),
+	)
+	// OPDS 2.0
+	Test(t,
+		Description("Kompanion OPDS 2.0 navigation"),
+		Get(basePath+"/opds/v2/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Headers("Content-Type").Contains("application/opds+json"),
+		Expect().Body().String().Contains("/opds/v2/newest/"),
+	)
+	Test(t,
+		Description("Kompanion OPDS 2.0 content negotiation"),
+		Get(basePath+"/opds/newest/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Send().Headers("Accept").Add("application/opds+json"),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Headers("Content-Type").Contains("application/opds+json"),
+		Expect().Body().String().Contains(`"publications"`),
+		Expect().Body().String().Contains(bookID),
+	)
+	// browse by author
+	Test(t,
+		Description("Kompanion Authors via OPDS"),
+		Get(basePath+"/opds/authors/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains("/opds/authors/Andy%20Wei"),
+	)
+	Test(t,
+		Description("Kompanion Languages via OPDS"),
+		Get(basePath+"/opds/languages/en/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
 	)
 	// download book
 	Test(t,
@@ -459,6 +534,71 @@ func TestHTTPKompanionOPDS(t *testing.T) {
 		Expect().Body().Bytes().Equal(bookContent),
 	)
 	// seach opds
+	Test(t,
+		Description("Kompanion OpenSearch description via OPDS"),
+		Get(basePath+"/opds/search.xml"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains("/opds/search/?q={searchTerms}"),
+	)
+	Test(t,
+		Description("Kompanion Search Books via OPDS"),
+		Get(basePath+"/opds/search/egg/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
+	)
+	Test(t,
+		Description("Kompanion Search Books via OPDS query string"),
+		Get(basePath+"/opds/search/?q=egg"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
+	)
+	Test(t,
+		Description("Kompanion Search Books via OPDS with slash in query"),
+		Get(basePath+"/opds/search/?q="+url.QueryEscape("AC/DC")),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+	)
+
+	// reading status shelves
+	documentID, err := utils.PartialMD5("book.epub")
+	if err != nil {
+		t.Fatalf("Failed to hash book: %s", err)
+	}
+	deviceName := generateDeviceName()
+	Test(t, setupDeviceSteps(client, deviceName))
+	Test(t,
+		Description("Koreader Put Book Progress"),
+		Put(basePath+"/syncs/progress"),
+		Send().Headers("Content-Type").Add("application/json"),
+		Send().Body().JSON(map[string]interface{}{
+			"document":   documentID,
+			"percentage": 0.5,
+			"progress":   "/body/DocFragment[3]",
+			"device":     "koreader",
+			"device_id":  "koreader",
+		}),
+		Send().Headers("x-auth-user").Add(deviceName),
+		Send().Headers("x-auth-key").Add(hashSyncPassword("password")),
+		Expect().Status().Equal(http.StatusOK),
+	)
+	Test(t,
+		Description("Kompanion Currently Reading via OPDS"),
+		Get(basePath+"/opds/reading/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().Contains(bookID),
+		Expect().Body().String().Contains("Read 50% on "+deviceName),
+	)
+	Test(t,
+		Description("Kompanion Not Started via OPDS"),
+		Get(basePath+"/opds/unread/"),
+		Send().Headers("Authorization").Add(basicAuth),
+		Expect().Status().Equal(http.StatusOK),
+		Expect().Body().String().NotContains(bookID),
+	)
 }
 
 func grabTestUser() (string, string) {
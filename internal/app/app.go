@@ -2,10 +2,12 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -19,8 +21,10 @@ import (
 	"github.com/vanadium23/kompanion/internal/stats"
 	"github.com/vanadium23/kompanion/internal/storage"
 	"github.com/vanadium23/kompanion/internal/sync"
+	"github.com/vanadium23/kompanion/pkg/converter"
 	"github.com/vanadium23/kompanion/pkg/httpserver"
 	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/metadata"
 	"github.com/vanadium23/kompanion/pkg/postgres"
 )
 
@@ -35,7 +39,7 @@ func Run(cfg *config.Config) {
 	}
 	defer pg.Close()
 
-	bookStorage, err := storage.NewStorage(cfg.BookStorage.Type, cfg.BookStorage.Path, pg)
+	bookStorage, err := storage.NewStorage(cfg.BookStorage.Type, cfg.BookStorage.Path, s3Config(cfg.BookStorage.S3), pg)
 	if err != nil {
 		l.Fatal(fmt.Errorf("app - Run - storage.NewStorage: %w", err))
 	}
@@ -58,13 +62,33 @@ func Run(cfg *config.Config) {
 	progress := sync.NewProgressSync(sync.NewProgressDatabaseRepo(pg))
 	shelf := library.NewBookShelf(bookStorage, library.NewBookDatabaseRepo(pg), l)
 	rs := stats.NewKOReaderPGStats(pg)
+	enricher := library.NewEnricher(shelf, metadataProviders(cfg.Metadata), l)
+	collections := library.NewBookCollections(library.NewCollectionDatabaseRepo(pg), l)
+	converters, err := bookConverters(cfg.Converter)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - bookConverters: %w", err))
+	}
+	conversions := library.NewConversionQueue(shelf, converters, cfg.Converter.QueueSize, cfg.Converter.Timeout, l)
+
+	// Background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go library.RunTrashPurge(ctx, shelf, time.Duration(cfg.BookStorage.TrashDays)*24*time.Hour, time.Hour, l)
+	if cfg.Inbox.Path != "" {
+		inbox, err := library.NewInbox(cfg.Inbox.Path, library.NewImporter(shelf, l), l)
+		if err != nil {
+			l.Fatal(fmt.Errorf("app - Run - library.NewInbox: %w", err))
+		}
+		go inbox.Run(ctx, cfg.Inbox.Interval)
+	}
+	go conversions.Run(ctx, cfg.Converter.Workers)
 
 	// HTTP Server
 	router := gin.New()
 	handler := router.Group(cfg.UrlPrefix)
-	web.NewRouter(handler, router, l, authService, progress, shelf, rs, cfg.Version)
+	web.NewRouter(handler, router, l, authService, progress, shelf, enricher, conversions, collections, rs, cfg.Version)
 	v1.NewRouter(handler, l, authService, progress, shelf)
-	opds.NewRouter(handler, l, authService, progress, shelf)
+	opds.NewRouter(handler, l, authService, progress, shelf, collections)
 	webdav.NewRouter(handler, authService, l, rs)
 	httpServer := httpserver.New(router, httpserver.Port(cfg.HTTP.Port))
 
@@ -85,3 +109,42 @@ func Run(cfg *config.Config) {
 		l.Error(fmt.Errorf("app - Run - httpServer.Shutdown: %w", err))
 	}
 }
+
+// metadataProviders creates online catalogs in configured order.
+func metadataProviders(cfg config.Metadata) []metadata.Provider {
+	providers := make([]metadata.Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		switch name {
+		case "openlibrary":
+			providers = append(providers, metadata.NewOpenLibrary(cfg.OpenLibraryURL, cfg.OpenLibraryCoversURL, nil))
+		case "googlebooks":
+			providers = append(providers, metadata.NewGoogleBooks(cfg.GoogleBooksURL, cfg.GoogleBooksKey, nil))
+		}
+	}
+	return providers
+}
+
+// s3Config passes bucket settings to storage.
+func s3Config(cfg config.S3) storage.S3Config {
+	return storage.S3Config{
+		Bucket:    cfg.Bucket,
+		Endpoint:  cfg.Endpoint,
+		Region:    cfg.Region,
+		AccessKey: cfg.AccessKey,
+		SecretKey: cfg.SecretKey,
+		PathStyle: cfg.PathStyle,
+	}
+}
+
+// bookConverters creates external converters in configured order.
+func bookConverters(cfg config.Converter) ([]converter.Converter, error) {
+	converters := make([]converter.Converter, 0, len(cfg.Commands))
+	for _, command := range cfg.Commands {
+		c, err := converter.NewCommand(command.Format, command.From, command.Command)
+		if err != nil {
+			return nil, err
+		}
+		converters = append(converters, c)
+	}
+	return converters, nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/vanadium23/kompanion/config"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/calibre"
+	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/postgres"
+)
+
+// Import stores all books from directory into library and prints report.
+// Calibre library is recognized by metadata.db and imported with its metadata.
+func Import(cfg *config.Config, dir string) {
+	l := logger.New(cfg.Log.Level)
+
+	pg, err := postgres.New(cfg.PG.URL, postgres.MaxPoolSize(cfg.PG.PoolMax))
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Import - postgres.New: %w", err))
+	}
+	defer pg.Close()
+
+	bookStorage, err := storage.NewStorage(cfg.BookStorage.Type, cfg.BookStorage.Path, s3Config(cfg.BookStorage.S3), pg)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Import - storage.NewStorage: %w", err))
+	}
+
+	shelf := library.NewBookShelf(bookStorage, library.NewBookDatabaseRepo(pg), l)
+	importer := library.NewImporter(shelf, l)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var report library.ImportReport
+	if calibre.IsLibrary(dir) {
+		l.Info("app - Import - calibre library found in %s", dir)
+		report, err = importer.ImportCalibre(ctx, dir)
+	} else {
+		report, err = importer.ImportDir(ctx, dir)
+	}
+	for _, result := range report.Results {
+		switch result.Status {
+		case library.ImportStored:
+			fmt.Printf("%-9s %s -> %s\n", result.Status, result.Name, result.Book.ID)
+		case library.ImportDuplicate:
+			fmt.Printf("%-9s %s = %s\n", result.Status, result.Name, result.Book.ID)
+		default:
+			fmt.Printf("%-9s %s: %s\n", result.Status, result.Name, result.Err)
+		}
+	}
+	fmt.Println(report.String())
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Import - importer.Import: %w", err))
+	}
+}
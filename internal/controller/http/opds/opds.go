@@ -3,8 +3,17 @@ package opds
 import (
 	"encoding/xml"
 	"fmt"
+	"html"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
 	"github.com/vanadium23/kompanion/internal/entity"
 	"github.com/vanadium23/kompanion/internal/library"
 )
@@ -15,45 +24,105 @@ const (
 	DirRel   = "subsection"
 	FileRel  = "http://opds-spec.org/acquisition"
 	CoverRel = "http://opds-spec.org/cover"
+	ImageRel = "http://opds-spec.org/image"
+	ThumbRel = "http://opds-spec.org/image/thumbnail"
+
+	AtomNS = "http://www.w3.org/2005/Atom"
+	DCNS   = "http://purl.org/dc/terms/"
+	OPDSNS = "http://opds-spec.org/2010/catalog"
+
+	// summaryLength limits plain text annotation shown in catalog lists.
+	summaryLength = 500
+
+	OpenSearchMime = "application/opensearchdescription+xml"
+	OpenSearchNS   = "http://a9.com/-/spec/opensearch/1.1/"
 )
 
 // Feed is a main frame of OPDS.
 type Feed struct {
-	XMLName xml.Name `xml:"feed"`
-	ID      string   `xml:"id"`
-	Title   string   `xml:"title"`
-	Xmlns   string   `xml:"xmlns,attr"`
-	Updated string   `xml:"updated"`
-	Link    []Link   `xml:"link"`
-	Entry   []Entry  `xml:"entry"`
+	XMLName   xml.Name `xml:"feed"`
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	XmlnsDC   string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS string   `xml:"xmlns:opds,attr"`
+	Updated   string   `xml:"updated"`
+	Link      []Link   `xml:"link"`
+	Entry     []Entry  `xml:"entry"`
 }
 
 // Link is link properties.
 type Link struct {
-	Href string `xml:"href,attr"`
-	Type string `xml:"type,attr"`
-	Rel  string `xml:"rel,attr,ommitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr"`
+	Rel   string `xml:"rel,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
 }
 
 // Entry is a struct of OPDS entry properties.
 type Entry struct {
-	ID      string  `xml:"id"`
-	Updated string  `xml:"updated"`
-	Title   string  `xml:"title"`
-	Author  Author  `xml:"author,ommitempty"`
-	Summary Summary `xml:"summary,ommitempty"`
-	Link    []Link  `xml:"link"`
+	ID         string     `xml:"id"`
+	Updated    string     `xml:"updated"`
+	Title      string     `xml:"title"`
+	Author     []Author   `xml:"author,omitempty"`
+	Summary    *Summary   `xml:"summary,omitempty"`
+	Content    *Summary   `xml:"content,omitempty"`
+	Category   []Category `xml:"category,omitempty"`
+	Language   string     `xml:"dc:language,omitempty"`
+	Issued     string     `xml:"dc:issued,omitempty"`
+	Publisher  string     `xml:"dc:publisher,omitempty"`
+	Identifier []string   `xml:"dc:identifier,omitempty"`
+	Link       []Link     `xml:"link"`
 }
 
 type Author struct {
 	Name string `xml:"name"`
 }
 
+// Summary is a text construct, used for summary and content.
 type Summary struct {
 	Type string `xml:"type,attr"`
 	Text string `xml:",chardata"`
 }
 
+type Category struct {
+	Scheme string `xml:"scheme,attr,omitempty"`
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr,omitempty"`
+}
+
+// OpenSearchDescription describes search endpoint for OPDS clients.
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	Url            []OpenSearchUrl `xml:"Url"`
+}
+
+type OpenSearchUrl struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+func BuildOpenSearchDescription(urlPrefix string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:          OpenSearchNS,
+		ShortName:      "KOmpanion",
+		Description:    "Search books by title, author, publisher, series or ISBN",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		Url: []OpenSearchUrl{
+			{
+				Type:     "application/atom+xml;profile=opds-catalog;kind=acquisition",
+				Template: urlPrefix + "/opds/search/?q={searchTerms}",
+			},
+		},
+	}
+}
+
 func BuildFeed(id, title, href string, entries []Entry, additionalLinks []Link, urlPrefix string) *Feed {
 	finalLinks := []Link{
 		{
@@ -67,38 +136,194 @@ func BuildFeed(id, title, href string, entries []Entry, additionalLinks []Link,
 			Rel:  "self",
 		},
 		{
-			Href: urlPrefix + "/opds/search/{searchTerms}/",
+			Href: urlPrefix + "/opds/search/?q={searchTerms}",
 			Type: "application/atom+xml",
 			Rel:  "search",
 		},
+		{
+			Href: urlPrefix + "/opds/search.xml",
+			Type: OpenSearchMime,
+			Rel:  "search",
+		},
 	}
 	finalLinks = append(finalLinks, additionalLinks...)
 	return &Feed{
-		ID:      id,
-		Title:   title,
-		Xmlns:   "http://www.w3.org/2005/Atom",
-		Updated: time.Now().UTC().Format(AtomTime),
-		Link:    finalLinks,
-		Entry:   entries,
+		ID:        id,
+		Title:     title,
+		Xmlns:     AtomNS,
+		XmlnsDC:   DCNS,
+		XmlnsOPDS: OPDSNS,
+		Updated:   time.Now().UTC().Format(AtomTime),
+		Link:      finalLinks,
+		Entry:     entries,
 	}
 }
 
-func translateBooksToEntries(books []entity.Book, urlPrefix string) []Entry {
+func translateBooksToEntries(books []entity.Book, progress map[string]entity.Progress, urlPrefix string) []Entry {
 	entries := make([]Entry, 0, len(books))
 	for _, book := range books {
+		entry := Entry{
+			ID:         book.ID,
+			Updated:    book.UpdatedAt.Format(AtomTime),
+			Title:      book.Title,
+			Language:   book.Language,
+			Publisher:  book.Publisher,
+			Identifier: bookIdentifiers(book),
+		}
+		// one acquisition link per format
+		for i, file := range book.Files() {
+			entry.Link = append(entry.Link, Link{
+				Href:  fileURL(urlPrefix, book.ID, file.DocumentID, i == 0),
+				Type:  file.MimeType(),
+				Rel:   FileRel,
+				Title: strings.ToUpper(file.Format),
+			})
+		}
+		if book.Author != "" {
+			entry.Author = []Author{{Name: book.Author}}
+		}
+		if book.Year > 0 {
+			entry.Issued = strconv.Itoa(book.Year)
+		}
+		note := progressNote(progress[book.DocumentID])
+		if book.Description != "" {
+			entry.Summary = &Summary{Type: "text", Text: truncate(plainText(book.Description), summaryLength)}
+			entry.Content = &Summary{Type: "text", Text: book.Description}
+			if looksLikeHTML(book.Description) {
+				entry.Content.Type = "html"
+			}
+		}
+		if note != "" {
+			if entry.Summary == nil {
+				entry.Summary = &Summary{Type: "text"}
+			}
+			entry.Summary.Text = strings.TrimSpace(note + "\n\n" + entry.Summary.Text)
+		}
+		if book.Series != "" {
+			entry.Category = append(entry.Category, Category{
+				Scheme: "urn:kompanion:series",
+				Term:   book.Series,
+				Label:  book.Series,
+			})
+		}
+		for _, subject := range book.Subjects {
+			entry.Category = append(entry.Category, Category{Term: subject, Label: subject})
+		}
+		// books without cover get generated one
+		entry.Link = append(entry.Link,
+			Link{
+				Href: fmt.Sprintf(urlPrefix+"/opds/book/%s/cover", book.ID),
+				Type: "image/jpeg",
+				Rel:  ImageRel,
+			},
+			Link{
+				Href: fmt.Sprintf(urlPrefix+"/opds/book/%s/thumbnail", book.ID),
+				Type: "image/jpeg",
+				Rel:  ThumbRel,
+			},
+		)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// fileURL returns download link of the book file, the main file
+// keeps its link, so clients, which saved it, can download it again.
+func fileURL(urlPrefix, bookID, documentID string, main bool) string {
+	if main {
+		return fmt.Sprintf(urlPrefix+"/opds/book/%s/download", bookID)
+	}
+	return fmt.Sprintf(urlPrefix+"/opds/book/%s/formats/%s/download", bookID, documentID)
+}
+
+// bookIdentifiers returns dc:identifier values: ISBN (if known) and book uuid.
+func bookIdentifiers(book entity.Book) []string {
+	identifiers := []string{}
+	isbn := strings.TrimSpace(book.ISBN)
+	switch {
+	case isbn == "":
+	case strings.HasPrefix(isbn, "urn:"):
+		identifiers = append(identifiers, isbn)
+	default:
+		identifiers = append(identifiers, "urn:isbn:"+isbn)
+	}
+	return append(identifiers, "urn:uuid:"+book.ID)
+}
+
+// progressNote describes the last synced reading position of the book.
+func progressNote(p entity.Progress) string {
+	if p.Document == "" {
+		return ""
+	}
+	note := fmt.Sprintf("Read %d%%", int(math.Round(p.Percentage*100)))
+	if p.Device != "" {
+		note += " on " + p.Device
+	}
+	if p.Timestamp > 0 {
+		note += ", " + time.Unix(p.Timestamp, 0).UTC().Format("2006-01-02")
+	}
+	return note
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+func looksLikeHTML(text string) bool {
+	return htmlTagRe.MatchString(text)
+}
+
+// plainText strips html tags and collapses whitespaces.
+func plainText(text string) string {
+	text = html.UnescapeString(htmlTagRe.ReplaceAllString(text, " "))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:length])) + "…"
+}
+
+func translateGroupsToEntries(groups []library.BookGroup, field, baseURL string) []Entry {
+	entries := make([]Entry, 0, len(groups))
+	for _, group := range groups {
+		entries = append(entries, Entry{
+			ID:      fmt.Sprintf("urn:kompanion:%s:%s", field, group.Value),
+			Updated: time.Now().UTC().Format(AtomTime),
+			Title:   groupTitle(field, group.Value),
+			Content: &Summary{
+				Type: "text",
+				Text: fmt.Sprintf("%d books", group.Count),
+			},
+			Link: []Link{
+				{
+					Href: baseURL + url.PathEscape(group.Value) + "/",
+					Type: "application/atom+xml;type=feed;profile=opds-catalog",
+					Rel:  DirRel,
+				},
+			},
+		})
+	}
+	return entries
+}
+
+func translateCollectionsToEntries(collections []entity.Collection, baseURL string) []Entry {
+	entries := make([]Entry, 0, len(collections))
+	for _, collection := range collections {
 		entries = append(entries, Entry{
-			ID:      book.ID,
-			Updated: book.UpdatedAt.Format(AtomTime),
-			Title:   book.Title,
-			Author: Author{
-				Name: book.Author,
+			ID:      "urn:kompanion:collection:" + collection.ID,
+			Updated: collection.UpdatedAt.UTC().Format(AtomTime),
+			Title:   collection.Name,
+			Content: &Summary{
+				Type: "text",
+				Text: fmt.Sprintf("%d books", collection.BookCount),
 			},
 			Link: []Link{
 				{
-					Href: fmt.Sprintf(urlPrefix+"/opds/book/%s/download", book.ID),
-					Type: book.MimeType(),
-					Rel:  FileRel,
-					// Mtime: book.UpdatedAt.Format(AtomTime),
+					Href: baseURL + url.PathEscape(collection.ID) + "/",
+					Type: "application/atom+xml;type=feed;profile=opds-catalog",
+					Rel:  DirRel,
 				},
 			},
 		})
@@ -106,6 +331,22 @@ func translateBooksToEntries(books []entity.Book, urlPrefix string) []Entry {
 	return entries
 }
 
+// groupTitle returns human readable name for the value of group field.
+func groupTitle(field, value string) string {
+	if field != library.GroupByLanguage {
+		return value
+	}
+	tag, err := language.Parse(value)
+	if err != nil {
+		return value
+	}
+	name := display.English.Tags().Name(tag)
+	if name == "" {
+		return value
+	}
+	return fmt.Sprintf("%s (%s)", name, value)
+}
+
 func formNavLinks(baseURL string, books library.PaginatedBookList) []Link {
 	links := []Link{
 		{
@@ -114,21 +355,21 @@ func formNavLinks(baseURL string, books library.PaginatedBookList) []Link {
 			Rel:  "start",
 		},
 		{
-			Href: fmt.Sprintf("%s?page=%d", baseURL, books.Last()),
+			Href: pageURL(baseURL, books.Last()),
 			Type: DirMime,
 			Rel:  "last",
 		},
 	}
 	if books.HasNext() {
 		links = append(links, Link{
-			Href: fmt.Sprintf("%s?page=%d", baseURL, books.Next()),
+			Href: pageURL(baseURL, books.Next()),
 			Type: DirMime,
 			Rel:  "next",
 		})
 	}
 	if books.HasPrev() {
 		links = append(links, Link{
-			Href: fmt.Sprintf("%s?page=%d", baseURL, books.Prev()),
+			Href: pageURL(baseURL, books.Prev()),
 			Type: DirMime,
 			Rel:  "prev",
 		})
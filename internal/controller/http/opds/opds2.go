@@ -0,0 +1,280 @@
+package opds
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+)
+
+// OPDS 2.0 is a JSON serialization of the catalog, see https://drafts.opds.io/opds-2.0
+const (
+	OPDS2Mime    = "application/opds+json"
+	OPDS2PubMime = "application/opds-publication+json"
+	SchemaBook   = "http://schema.org/Book"
+)
+
+// FeedV2 is a main frame of OPDS 2.0 catalog.
+type FeedV2 struct {
+	Metadata     FeedMetadataV2 `json:"metadata"`
+	Links        []LinkV2       `json:"links"`
+	Navigation   []LinkV2       `json:"navigation,omitempty"`
+	Facets       []FacetV2      `json:"facets,omitempty"`
+	Publications []Publication  `json:"publications,omitempty"`
+}
+
+type FeedMetadataV2 struct {
+	Title         string `json:"title"`
+	Modified      string `json:"modified,omitempty"`
+	NumberOfItems int    `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int    `json:"itemsPerPage,omitempty"`
+	CurrentPage   int    `json:"currentPage,omitempty"`
+}
+
+// LinkV2 is a link object of OPDS 2.0 (Readium Web Publication Manifest).
+type LinkV2 struct {
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Rel        string          `json:"rel,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Properties *LinkProperties `json:"properties,omitempty"`
+}
+
+type LinkProperties struct {
+	NumberOfItems int `json:"numberOfItems,omitempty"`
+}
+
+type FacetV2 struct {
+	Metadata FeedMetadataV2 `json:"metadata"`
+	Links    []LinkV2       `json:"links"`
+}
+
+type Publication struct {
+	Metadata PublicationMetadata `json:"metadata"`
+	Links    []LinkV2            `json:"links"`
+	Images   []LinkV2            `json:"images,omitempty"`
+}
+
+type PublicationMetadata struct {
+	Type        string        `json:"@type"`
+	Identifier  string        `json:"identifier,omitempty"`
+	Title       string        `json:"title"`
+	Author      []Contributor `json:"author,omitempty"`
+	Publisher   []Contributor `json:"publisher,omitempty"`
+	Language    string        `json:"language,omitempty"`
+	Published   string        `json:"published,omitempty"`
+	Modified    string        `json:"modified,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Subject     []string      `json:"subject,omitempty"`
+	BelongsTo   *BelongsTo    `json:"belongsTo,omitempty"`
+}
+
+type Contributor struct {
+	Name string `json:"name"`
+}
+
+type BelongsTo struct {
+	Series []Contributor `json:"series,omitempty"`
+}
+
+func BuildFeedV2(title, href string, navigation []LinkV2, publications []Publication, additionalLinks []LinkV2, urlPrefix string) *FeedV2 {
+	links := []LinkV2{
+		{
+			Href: href,
+			Type: OPDS2Mime,
+			Rel:  "self",
+		},
+		{
+			Href: urlPrefix + "/opds/v2/",
+			Type: OPDS2Mime,
+			Rel:  "start",
+		},
+		{
+			Href:      urlPrefix + "/opds/v2/search{?query}",
+			Type:      OPDS2Mime,
+			Rel:       "search",
+			Templated: true,
+		},
+	}
+	links = append(links, additionalLinks...)
+	return &FeedV2{
+		Metadata: FeedMetadataV2{
+			Title: title,
+		},
+		Links:        links,
+		Navigation:   navigation,
+		Publications: publications,
+	}
+}
+
+// BuildPaginatedFeedV2 fills pagination metadata and links of a publications feed.
+func BuildPaginatedFeedV2(title, baseURL string, books library.PaginatedBookList, progress map[string]entity.Progress, currentPage, perPage int, urlPrefix string) *FeedV2 {
+	feed := BuildFeedV2(
+		title,
+		pageURL(baseURL, currentPage),
+		nil,
+		translateBooksToPublications(books.Books, progress, urlPrefix),
+		formNavLinksV2(baseURL, books),
+		urlPrefix,
+	)
+	feed.Metadata.NumberOfItems = books.TotalCount()
+	feed.Metadata.ItemsPerPage = perPage
+	feed.Metadata.CurrentPage = currentPage
+	return feed
+}
+
+func translateBooksToPublications(books []entity.Book, progress map[string]entity.Progress, urlPrefix string) []Publication {
+	publications := make([]Publication, 0, len(books))
+	for _, book := range books {
+		publication := Publication{
+			Metadata: PublicationMetadata{
+				Type:        SchemaBook,
+				Identifier:  bookIdentifiers(book)[0],
+				Title:       book.Title,
+				Language:    book.Language,
+				Modified:    book.UpdatedAt.UTC().Format(AtomTime),
+				Description: book.Description,
+				Subject:     book.Subjects,
+			},
+		}
+		for i, file := range book.Files() {
+			publication.Links = append(publication.Links, LinkV2{
+				Href:  fileURL(urlPrefix, book.ID, file.DocumentID, i == 0),
+				Type:  file.MimeType(),
+				Rel:   FileRel,
+				Title: strings.ToUpper(file.Format),
+			})
+		}
+		if book.Author != "" {
+			publication.Metadata.Author = []Contributor{{Name: book.Author}}
+		}
+		if book.Publisher != "" {
+			publication.Metadata.Publisher = []Contributor{{Name: book.Publisher}}
+		}
+		if book.Year > 0 {
+			publication.Metadata.Published = strconv.Itoa(book.Year)
+		}
+		if note := progressNote(progress[book.DocumentID]); note != "" {
+			publication.Metadata.Description = strings.TrimSpace(note + "\n\n" + book.Description)
+		}
+		if book.Series != "" {
+			publication.Metadata.BelongsTo = &BelongsTo{
+				Series: []Contributor{{Name: book.Series}},
+			}
+		}
+		// books without cover get generated one
+		publication.Images = []LinkV2{
+			{
+				Href: fmt.Sprintf(urlPrefix+"/opds/book/%s/cover", book.ID),
+				Type: "image/jpeg",
+				Rel:  "cover",
+			},
+			{
+				Href: fmt.Sprintf(urlPrefix+"/opds/book/%s/thumbnail", book.ID),
+				Type: "image/jpeg",
+				Rel:  "thumbnail",
+			},
+		}
+		publications = append(publications, publication)
+	}
+	return publications
+}
+
+func translateGroupsToNavigation(groups []library.BookGroup, field, baseURL string) []LinkV2 {
+	navigation := make([]LinkV2, 0, len(groups))
+	for _, group := range groups {
+		navigation = append(navigation, LinkV2{
+			Href:  baseURL + url.PathEscape(group.Value) + "/",
+			Type:  OPDS2Mime,
+			Title: groupTitle(field, group.Value),
+			Properties: &LinkProperties{
+				NumberOfItems: group.Count,
+			},
+		})
+	}
+	return navigation
+}
+
+func translateCollectionsToNavigation(collections []entity.Collection, baseURL string) []LinkV2 {
+	navigation := make([]LinkV2, 0, len(collections))
+	for _, collection := range collections {
+		navigation = append(navigation, LinkV2{
+			Href:  baseURL + url.PathEscape(collection.ID) + "/",
+			Type:  OPDS2Mime,
+			Title: collection.Name,
+			Properties: &LinkProperties{
+				NumberOfItems: collection.BookCount,
+			},
+		})
+	}
+	return navigation
+}
+
+func formNavLinksV2(baseURL string, books library.PaginatedBookList) []LinkV2 {
+	links := []LinkV2{
+		{
+			Href: pageURL(baseURL, books.First()),
+			Type: OPDS2Mime,
+			Rel:  "first",
+		},
+		{
+			Href: pageURL(baseURL, books.Last()),
+			Type: OPDS2Mime,
+			Rel:  "last",
+		},
+	}
+	if books.HasNext() {
+		links = append(links, LinkV2{
+			Href: pageURL(baseURL, books.Next()),
+			Type: OPDS2Mime,
+			Rel:  "next",
+		})
+	}
+	if books.HasPrev() {
+		links = append(links, LinkV2{
+			Href: pageURL(baseURL, books.Prev()),
+			Type: OPDS2Mime,
+			Rel:  "previous",
+		})
+	}
+	return links
+}
+
+// pageURL adds page parameter to url, which may already have a query.
+func pageURL(baseURL string, page int) string {
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%spage=%d", baseURL, separator, page)
+}
+
+// prefersOPDS2 checks Accept header of OPDS client and returns true
+// if JSON catalog is ranked higher than Atom one.
+func prefersOPDS2(accept string) bool {
+	jsonQ, atomQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case OPDS2Mime, OPDS2PubMime:
+			jsonQ = max(jsonQ, q)
+		case "application/atom+xml", "application/xml", "text/xml":
+			atomQ = max(atomQ, q)
+		}
+	}
+	return jsonQ > atomQ
+}
@@ -0,0 +1,128 @@
+package opds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+)
+
+func TestPrefersOPDS2(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "missing header", accept: "", want: false},
+		{name: "any type", accept: "*/*", want: false},
+		{name: "atom", accept: "application/atom+xml;profile=opds-catalog;kind=navigation", want: false},
+		{name: "opds2", accept: "application/opds+json", want: true},
+		{name: "opds2 publication", accept: "application/opds-publication+json", want: true},
+		{name: "opds2 with any type", accept: "application/opds+json, */*;q=0.8", want: true},
+		{name: "atom ranked higher", accept: "application/opds+json;q=0.5, application/atom+xml", want: false},
+		{name: "opds2 ranked higher", accept: "application/atom+xml;q=0.9, application/opds+json", want: true},
+		{name: "same rank", accept: "application/opds+json, application/xml", want: false},
+		{name: "invalid q", accept: "application/opds+json;q=high, application/atom+xml;q=0.5", want: true},
+		{name: "malformed part", accept: "application/opds+json;;, text/xml", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, prefersOPDS2(tt.accept))
+		})
+	}
+}
+
+func TestNegotiateVary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/opds/", negotiate(
+		func(c *gin.Context) { c.String(http.StatusOK, "atom") },
+		func(c *gin.Context) { c.String(http.StatusOK, "json") },
+	))
+
+	for accept, body := range map[string]string{"application/opds+json": "json", "application/atom+xml": "atom"} {
+		req := httptest.NewRequest(http.MethodGet, "/opds/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, body, w.Body.String())
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	}
+}
+
+func TestBuildPaginatedFeedV2(t *testing.T) {
+	books := make([]entity.Book, 10)
+	for i := range books {
+		books[i] = entity.Book{ID: "1", Title: "Dune", FilePath: "1.epub", DocumentID: "hash", UpdatedAt: time.Now()}
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		page    int
+		links   map[string]string
+	}{
+		{
+			name:    "first page",
+			baseURL: "/opds/v2/newest/",
+			page:    1,
+			links: map[string]string{
+				"self":  "/opds/v2/newest/?page=1",
+				"first": "/opds/v2/newest/?page=1",
+				"last":  "/opds/v2/newest/?page=3",
+				"next":  "/opds/v2/newest/?page=2",
+			},
+		},
+		{
+			name:    "middle page with query",
+			baseURL: "/opds/v2/search?query=dune",
+			page:    2,
+			links: map[string]string{
+				"self":     "/opds/v2/search?query=dune&page=2",
+				"first":    "/opds/v2/search?query=dune&page=1",
+				"last":     "/opds/v2/search?query=dune&page=3",
+				"next":     "/opds/v2/search?query=dune&page=3",
+				"previous": "/opds/v2/search?query=dune&page=1",
+			},
+		},
+		{
+			name:    "last page",
+			baseURL: "/opds/v2/newest/",
+			page:    3,
+			links: map[string]string{
+				"self":     "/opds/v2/newest/?page=3",
+				"first":    "/opds/v2/newest/?page=1",
+				"last":     "/opds/v2/newest/?page=3",
+				"previous": "/opds/v2/newest/?page=2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := library.NewPaginatedBookList(books, 10, tt.page, 25)
+			feed := BuildPaginatedFeedV2("Newest", tt.baseURL, list, nil, tt.page, 10, "")
+
+			assert.Equal(t, "Newest", feed.Metadata.Title)
+			assert.Equal(t, 25, feed.Metadata.NumberOfItems)
+			assert.Equal(t, 10, feed.Metadata.ItemsPerPage)
+			assert.Equal(t, tt.page, feed.Metadata.CurrentPage)
+			require.Len(t, feed.Publications, len(books))
+
+			links := map[string]string{}
+			for _, link := range feed.Links {
+				if link.Rel == "start" || link.Rel == "search" {
+					continue
+				}
+				links[link.Rel] = link.Href
+			}
+			assert.Equal(t, tt.links, links)
+		})
+	}
+}
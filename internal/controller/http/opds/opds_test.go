@@ -0,0 +1,45 @@
+package opds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+)
+
+func TestFeedSubjects(t *testing.T) {
+	books := []entity.Book{{
+		ID: "1", Title: "Dune", Author: "Frank Herbert", FilePath: "1.epub", DocumentID: "hash",
+		Series: "Dune Chronicles", Subjects: []string{"Fiction", "Science fiction"}, UpdatedAt: time.Now(),
+	}}
+
+	feed := BuildFeed("urn:kompanion:newest", "KOmpanion library", "/opds/newest/", translateBooksToEntries(books, nil, ""), nil, "")
+	body, err := xml.Marshal(feed)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<category scheme="urn:kompanion:series" term="Dune Chronicles" label="Dune Chronicles"></category>`)
+	assert.Contains(t, string(body), `<category term="Fiction" label="Fiction"></category>`)
+	assert.Contains(t, string(body), `<category term="Science fiction" label="Science fiction"></category>`)
+
+	body, err = json.Marshal(translateBooksToPublications(books, nil, ""))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"subject":["Fiction","Science fiction"]`)
+}
+
+func TestSearchTemplate(t *testing.T) {
+	body, err := xml.Marshal(BuildOpenSearchDescription("/kompanion"))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `template="/kompanion/opds/search/?q={searchTerms}"`)
+
+	books := library.NewPaginatedBookList(make([]entity.Book, 10), 10, 2, 25)
+	feed := BuildFeed("urn:kompanion:search", "Search: AC/DC", "/opds/search/?q=AC%2FDC", nil, formNavLinks("/opds/search/?q=AC%2FDC", books), "")
+	body, err = xml.Marshal(feed)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `href="/opds/search/?q={searchTerms}"`)
+	assert.Contains(t, string(body), `href="/opds/search/?q=AC%2FDC&amp;page=3" type="application/atom+xml;profile=opds-catalog;kind=navigation" rel="next"`)
+}
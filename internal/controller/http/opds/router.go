@@ -1,22 +1,54 @@
 package opds
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vanadium23/kompanion/internal/auth"
+	"github.com/vanadium23/kompanion/internal/entity"
 	"github.com/vanadium23/kompanion/internal/library"
 	"github.com/vanadium23/kompanion/internal/sync"
 	"github.com/vanadium23/kompanion/pkg/logger"
 )
 
+// groupShelf is a navigation shelf over distinct values of a book field.
+type groupShelf struct {
+	field string
+	path  string
+	title string
+}
+
+var groupShelves = []groupShelf{
+	{library.GroupByAuthor, "authors", "By Author"},
+	{library.GroupBySeries, "series", "By Series"},
+	{library.GroupByPublisher, "publishers", "By Publisher"},
+	{library.GroupByLanguage, "languages", "By Language"},
+}
+
+// statusShelf is a shelf of books with the same reading status.
+type statusShelf struct {
+	status string
+	path   string
+	title  string
+}
+
+var statusShelves = []statusShelf{
+	{library.StatusReading, "reading", "Currently reading"},
+	{library.StatusFinished, "finished", "Finished"},
+	{library.StatusUnread, "unread", "Not started"},
+}
+
 type OPDSRouter struct {
-	urlPrefix string
-	books     library.Shelf
-	logger    logger.Interface
+	urlPrefix   string
+	books       library.Shelf
+	collections library.Collections
+	progress    sync.Progress
+	logger      logger.Interface
 }
 
 func NewRouter(
@@ -24,17 +56,46 @@ func NewRouter(
 	l logger.Interface,
 	a auth.AuthInterface,
 	p sync.Progress,
-	shelf library.Shelf) {
+	shelf library.Shelf,
+	collections library.Collections) {
 	urlPrefix := strings.TrimSuffix(handler.BasePath(), "/")
-	sh := &OPDSRouter{urlPrefix, shelf, l}
+	sh := &OPDSRouter{urlPrefix, shelf, collections, p, l}
 
 	h := handler.Group("/opds")
 	h.Use(basicAuth(a))
 	{
-		h.GET("/", sh.listShelves)
-		h.GET("/newest/", sh.listNewest)
+		h.GET("/", negotiate(sh.listShelves, sh.listShelvesV2))
+		h.GET("/newest/", negotiate(sh.listNewest, sh.listNewestV2))
+		for _, s := range statusShelves {
+			h.GET("/"+s.path+"/", negotiate(sh.listStatus(s), sh.listStatusV2(s)))
+		}
+		for _, g := range groupShelves {
+			h.GET("/"+g.path+"/*value", negotiate(sh.browseGroup(g), sh.browseGroupV2(g)))
+		}
+		h.GET("/collections/*collectionID", negotiate(sh.browseCollections, sh.browseCollectionsV2))
 		h.GET("/book/:bookID/download", sh.downloadBook)
-		// TODO: search
+		h.GET("/book/:bookID/formats/:documentID/download", sh.downloadBookFormat)
+		h.GET("/book/:bookID/cover", sh.viewCover)
+		h.GET("/book/:bookID/thumbnail", sh.viewThumbnail)
+		h.GET("/search.xml", sh.openSearchDescription)
+		h.GET("/search/", negotiate(sh.searchBooks, sh.searchBooksV2))
+		// path form of the query is kept for clients, which saved previous template
+		h.GET("/search/:searchTerms/", negotiate(sh.searchBooks, sh.searchBooksV2))
+	}
+
+	// OPDS 2.0
+	v2 := h.Group("/v2")
+	{
+		v2.GET("/", sh.listShelvesV2)
+		v2.GET("/newest/", sh.listNewestV2)
+		for _, s := range statusShelves {
+			v2.GET("/"+s.path+"/", sh.listStatusV2(s))
+		}
+		for _, g := range groupShelves {
+			v2.GET("/"+g.path+"/*value", sh.browseGroupV2(g))
+		}
+		v2.GET("/collections/*collectionID", sh.browseCollectionsV2)
+		v2.GET("/search", sh.searchBooksV2)
 	}
 }
 
@@ -52,6 +113,43 @@ func (r *OPDSRouter) listShelves(c *gin.Context) {
 			},
 		},
 	}
+	for _, s := range statusShelves {
+		shelves = append(shelves, Entry{
+			ID:      "urn:kompanion:" + s.path,
+			Updated: time.Now().UTC().Format(AtomTime),
+			Title:   s.title,
+			Link: []Link{
+				{
+					Href: r.urlPrefix + "/opds/" + s.path + "/",
+					Type: "application/atom+xml;type=feed;profile=opds-catalog",
+				},
+			},
+		})
+	}
+	for _, g := range groupShelves {
+		shelves = append(shelves, Entry{
+			ID:      "urn:kompanion:" + g.path,
+			Updated: time.Now().UTC().Format(AtomTime),
+			Title:   g.title,
+			Link: []Link{
+				{
+					Href: r.urlPrefix + "/opds/" + g.path + "/",
+					Type: DirMime,
+				},
+			},
+		})
+	}
+	shelves = append(shelves, Entry{
+		ID:      "urn:kompanion:collections",
+		Updated: time.Now().UTC().Format(AtomTime),
+		Title:   "Collections",
+		Link: []Link{
+			{
+				Href: r.urlPrefix + "/opds/collections/",
+				Type: DirMime,
+			},
+		},
+	})
 	links := []Link{}
 	feed := BuildFeed("urn:kompanion:main", "KOmpanion library", r.urlPrefix+"/opds", shelves, links, r.urlPrefix)
 	c.XML(http.StatusOK, feed)
@@ -70,12 +168,186 @@ func (r *OPDSRouter) listNewest(c *gin.Context) {
 		return
 	}
 	baseUrl := r.urlPrefix + "/opds/newest/"
-	entries := translateBooksToEntries(books.Books, r.urlPrefix)
+	entries := translateBooksToEntries(books.Books, r.readingProgress(c.Request.Context(), books.Books), r.urlPrefix)
 	navLinks := formNavLinks(baseUrl, books)
 	feed := BuildFeed("urn:kompanion:newest", "KOmpanion library", baseUrl, entries, navLinks, r.urlPrefix)
 	c.XML(http.StatusOK, feed)
 }
 
+// listStatus lists books by reading status, recently read first.
+func (r *OPDSRouter) listStatus(s statusShelf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pageStr := c.Query("page")
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			page = 1
+		}
+		filter := library.BookFilter{Status: s.status}
+		books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, "last_activity", "desc", page, 10)
+		if err != nil {
+			r.logger.Error("failed to list books by status", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+			return
+		}
+		baseUrl := r.urlPrefix + "/opds/" + s.path + "/"
+		entries := translateBooksToEntries(books.Books, r.readingProgress(c.Request.Context(), books.Books), r.urlPrefix)
+		navLinks := formNavLinks(baseUrl, books)
+		feed := BuildFeed("urn:kompanion:"+s.path, s.title, baseUrl, entries, navLinks, r.urlPrefix)
+		c.XML(http.StatusOK, feed)
+	}
+}
+
+// readingProgress fetches last synced progress of books by their document id.
+func (r *OPDSRouter) readingProgress(ctx context.Context, books []entity.Book) map[string]entity.Progress {
+	documentIDs := make([]string, 0, len(books))
+	for _, book := range books {
+		if book.DocumentID != "" {
+			documentIDs = append(documentIDs, book.DocumentID)
+		}
+	}
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	progress, err := r.progress.FetchMany(ctx, documentIDs)
+	if err != nil {
+		r.logger.Error("failed to fetch progress", err)
+		return nil
+	}
+	return progress
+}
+
+// browseGroup lists distinct values of the field on the root of the shelf
+// and books with the chosen value below it.
+func (r *OPDSRouter) browseGroup(g groupShelf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := strings.Trim(c.Param("value"), "/")
+		if value == "" {
+			r.listGroups(c, g)
+			return
+		}
+		r.listGroupBooks(c, g, value)
+	}
+}
+
+func (r *OPDSRouter) listGroups(c *gin.Context, g groupShelf) {
+	groups, err := r.books.ListGroups(c.Request.Context(), g.field)
+	if err != nil {
+		r.logger.Error("failed to list groups", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/" + g.path + "/"
+	entries := translateGroupsToEntries(groups, g.field, baseUrl)
+	feed := BuildFeed("urn:kompanion:"+g.path, g.title, baseUrl, entries, []Link{}, r.urlPrefix)
+	c.XML(http.StatusOK, feed)
+}
+
+func (r *OPDSRouter) listGroupBooks(c *gin.Context, g groupShelf, value string) {
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+	filter, err := library.FilterByGroup(g.field, value)
+	if err != nil {
+		r.logger.Error("failed to build filter", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	sortBy := "title"
+	if g.field == library.GroupBySeries {
+		sortBy = "series_index"
+	}
+	books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, sortBy, "asc", page, 10)
+	if err != nil {
+		r.logger.Error("failed to list books by group", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/" + g.path + "/" + url.PathEscape(value) + "/"
+	entries := translateBooksToEntries(books.Books, r.readingProgress(c.Request.Context(), books.Books), r.urlPrefix)
+	navLinks := formNavLinks(baseUrl, books)
+	feed := BuildFeed("urn:kompanion:"+g.path+":"+value, groupTitle(g.field, value), baseUrl, entries, navLinks, r.urlPrefix)
+	c.XML(http.StatusOK, feed)
+}
+
+// browseCollections lists collections on the root and books of the collection below it.
+func (r *OPDSRouter) browseCollections(c *gin.Context) {
+	collectionID := strings.Trim(c.Param("collectionID"), "/")
+	if collectionID == "" {
+		r.listCollections(c)
+		return
+	}
+	r.listCollectionBooks(c, collectionID)
+}
+
+func (r *OPDSRouter) listCollections(c *gin.Context) {
+	collections, err := r.collections.ListCollections(c.Request.Context())
+	if err != nil {
+		r.logger.Error("failed to list collections", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/collections/"
+	entries := translateCollectionsToEntries(collections, baseUrl)
+	feed := BuildFeed("urn:kompanion:collections", "Collections", baseUrl, entries, []Link{}, r.urlPrefix)
+	c.XML(http.StatusOK, feed)
+}
+
+func (r *OPDSRouter) listCollectionBooks(c *gin.Context, collectionID string) {
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+	collection, err := r.collections.ViewCollection(c.Request.Context(), collectionID)
+	if err != nil {
+		r.logger.Error("failed to view collection", err)
+		c.JSON(http.StatusNotFound, gin.H{"message": "collection not found"})
+		return
+	}
+	filter := library.BookFilter{Collection: collection.ID}
+	books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, "title", "asc", page, 10)
+	if err != nil {
+		r.logger.Error("failed to list books by collection", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/collections/" + url.PathEscape(collection.ID) + "/"
+	entries := translateBooksToEntries(books.Books, r.readingProgress(c.Request.Context(), books.Books), r.urlPrefix)
+	navLinks := formNavLinks(baseUrl, books)
+	feed := BuildFeed("urn:kompanion:collection:"+collection.ID, collection.Name, baseUrl, entries, navLinks, r.urlPrefix)
+	c.XML(http.StatusOK, feed)
+}
+
+func (r *OPDSRouter) searchBooks(c *gin.Context) {
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+	searchTerms := c.Query("q")
+	if searchTerms == "" {
+		searchTerms = c.Param("searchTerms")
+	}
+	books, err := r.books.SearchBooks(c.Request.Context(), searchTerms, page, 10)
+	if err != nil {
+		r.logger.Error("failed to search books", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/search/?" + url.Values{"q": {searchTerms}}.Encode()
+	entries := translateBooksToEntries(books.Books, r.readingProgress(c.Request.Context(), books.Books), r.urlPrefix)
+	navLinks := formNavLinks(baseUrl, books)
+	feed := BuildFeed("urn:kompanion:search", "Search: "+searchTerms, baseUrl, entries, navLinks, r.urlPrefix)
+	c.XML(http.StatusOK, feed)
+}
+
+func (r *OPDSRouter) openSearchDescription(c *gin.Context) {
+	c.Header("Content-Type", OpenSearchMime)
+	c.XML(http.StatusOK, BuildOpenSearchDescription(r.urlPrefix))
+}
+
 func (r *OPDSRouter) downloadBook(c *gin.Context) {
 	bookID := c.Param("bookID")
 
@@ -92,6 +364,50 @@ func (r *OPDSRouter) downloadBook(c *gin.Context) {
 	c.File(file.Name())
 }
 
+func (r *OPDSRouter) downloadBookFormat(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	book, file, err := r.books.DownloadBookFormat(c.Request.Context(), bookID, c.Param("documentID"))
+	if err != nil {
+		r.logger.Error(err, "http - opds - downloadBookFormat")
+		c.JSON(http.StatusNotFound, gin.H{"message": "book not found"})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", "attachment; filename="+book.Filename())
+	c.Header("Content-Type", "application/octet-stream")
+	c.File(file.Name())
+}
+
+func (r *OPDSRouter) viewCover(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	cover, err := r.books.ViewCover(c.Request.Context(), bookID)
+	if err != nil {
+		r.logger.Error(err, "http - opds - viewCover")
+		c.JSON(http.StatusNotFound, gin.H{"message": "cover not found"})
+		return
+	}
+	defer cover.Close()
+
+	c.File(cover.Name())
+}
+
+func (r *OPDSRouter) viewThumbnail(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	cover, err := r.books.ViewCoverThumbnail(c.Request.Context(), bookID, library.CoverSizes["small"])
+	if err != nil {
+		r.logger.Error(err, "http - opds - viewThumbnail")
+		c.JSON(http.StatusNotFound, gin.H{"message": "cover not found"})
+		return
+	}
+	defer cover.Close()
+
+	c.File(cover.Name())
+}
+
 func basicAuth(auth auth.AuthInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		username, password, ok := c.Request.BasicAuth()
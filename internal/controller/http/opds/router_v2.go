@@ -0,0 +1,286 @@
+package opds
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/utils"
+)
+
+// negotiate serves OPDS 2.0 JSON on the same route,
+// if client asks for it in Accept header.
+func negotiate(atom, json gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// caches must not serve one representation to clients asking for another
+		c.Header("Vary", "Accept")
+		if prefersOPDS2(c.GetHeader("Accept")) {
+			json(c)
+			return
+		}
+		atom(c)
+	}
+}
+
+func (r *OPDSRouter) renderV2(c *gin.Context, feed *FeedV2) {
+	c.Header("Content-Type", OPDS2Mime)
+	c.JSON(http.StatusOK, feed)
+}
+
+func (r *OPDSRouter) listShelvesV2(c *gin.Context) {
+	navigation := []LinkV2{
+		{
+			Href:  r.urlPrefix + "/opds/v2/newest/",
+			Type:  OPDS2Mime,
+			Title: "By Newest",
+		},
+	}
+	for _, s := range statusShelves {
+		navigation = append(navigation, LinkV2{
+			Href:  r.urlPrefix + "/opds/v2/" + s.path + "/",
+			Type:  OPDS2Mime,
+			Title: s.title,
+		})
+	}
+	for _, g := range groupShelves {
+		navigation = append(navigation, LinkV2{
+			Href:  r.urlPrefix + "/opds/v2/" + g.path + "/",
+			Type:  OPDS2Mime,
+			Title: g.title,
+		})
+	}
+	navigation = append(navigation, LinkV2{
+		Href:  r.urlPrefix + "/opds/v2/collections/",
+		Type:  OPDS2Mime,
+		Title: "Collections",
+	})
+	feed := BuildFeedV2("KOmpanion library", r.urlPrefix+"/opds/v2/", navigation, nil, nil, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) listNewestV2(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	order := c.Query("order")
+	lang := c.Query("language")
+
+	sortBy, sortOrder := "created_at", "desc"
+	if order == "title" {
+		sortBy, sortOrder = "title", "asc"
+	}
+	filter := library.BookFilter{Language: lang}
+	books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, sortBy, sortOrder, page, 10)
+	if err != nil {
+		r.logger.Error("failed to list newest books", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	languages, err := r.books.ListGroups(c.Request.Context(), library.GroupByLanguage)
+	if err != nil {
+		r.logger.Error("failed to list languages", err)
+		languages = []library.BookGroup{}
+	}
+
+	rootUrl := r.urlPrefix + "/opds/v2/newest/"
+	feed := BuildPaginatedFeedV2("KOmpanion library", facetURL(rootUrl, order, lang), books, r.readingProgress(c.Request.Context(), books.Books), page, 10, r.urlPrefix)
+	feed.Facets = newestFacets(rootUrl, order, lang, languages)
+	r.renderV2(c, feed)
+}
+
+// newestFacets returns facets to change order and language of newest shelf.
+func newestFacets(rootURL, order, lang string, languages []library.BookGroup) []FacetV2 {
+	orderFacet := FacetV2{
+		Metadata: FeedMetadataV2{Title: "Order"},
+		Links: []LinkV2{
+			{
+				Href:  facetURL(rootURL, "", lang),
+				Type:  OPDS2Mime,
+				Title: "Newest",
+				Rel:   utils.If(order != "title", "self", ""),
+			},
+			{
+				Href:  facetURL(rootURL, "title", lang),
+				Type:  OPDS2Mime,
+				Title: "Title",
+				Rel:   utils.If(order == "title", "self", ""),
+			},
+		},
+	}
+	languageFacet := FacetV2{
+		Metadata: FeedMetadataV2{Title: "Language"},
+		Links: []LinkV2{
+			{
+				Href:  facetURL(rootURL, order, ""),
+				Type:  OPDS2Mime,
+				Title: "All",
+				Rel:   utils.If(lang == "", "self", ""),
+			},
+		},
+	}
+	for _, l := range languages {
+		languageFacet.Links = append(languageFacet.Links, LinkV2{
+			Href:       facetURL(rootURL, order, l.Value),
+			Type:       OPDS2Mime,
+			Title:      groupTitle(library.GroupByLanguage, l.Value),
+			Rel:        utils.If(lang == l.Value, "self", ""),
+			Properties: &LinkProperties{NumberOfItems: l.Count},
+		})
+	}
+	return []FacetV2{orderFacet, languageFacet}
+}
+
+func facetURL(rootURL, order, lang string) string {
+	query := url.Values{}
+	if order != "" {
+		query.Set("order", order)
+	}
+	if lang != "" {
+		query.Set("language", lang)
+	}
+	if len(query) == 0 {
+		return rootURL
+	}
+	return rootURL + "?" + query.Encode()
+}
+
+func (r *OPDSRouter) browseGroupV2(g groupShelf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := strings.Trim(c.Param("value"), "/")
+		if value == "" {
+			r.listGroupsV2(c, g)
+			return
+		}
+		r.listGroupBooksV2(c, g, value)
+	}
+}
+
+func (r *OPDSRouter) listGroupsV2(c *gin.Context, g groupShelf) {
+	groups, err := r.books.ListGroups(c.Request.Context(), g.field)
+	if err != nil {
+		r.logger.Error("failed to list groups", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/v2/" + g.path + "/"
+	navigation := translateGroupsToNavigation(groups, g.field, baseUrl)
+	feed := BuildFeedV2(g.title, baseUrl, navigation, nil, nil, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) listGroupBooksV2(c *gin.Context, g groupShelf, value string) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	filter, err := library.FilterByGroup(g.field, value)
+	if err != nil {
+		r.logger.Error("failed to build filter", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	sortBy := "title"
+	if g.field == library.GroupBySeries {
+		sortBy = "series_index"
+	}
+	books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, sortBy, "asc", page, 10)
+	if err != nil {
+		r.logger.Error("failed to list books by group", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/v2/" + g.path + "/" + url.PathEscape(value) + "/"
+	feed := BuildPaginatedFeedV2(groupTitle(g.field, value), baseUrl, books, r.readingProgress(c.Request.Context(), books.Books), page, 10, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) browseCollectionsV2(c *gin.Context) {
+	collectionID := strings.Trim(c.Param("collectionID"), "/")
+	if collectionID == "" {
+		r.listCollectionsV2(c)
+		return
+	}
+	r.listCollectionBooksV2(c, collectionID)
+}
+
+func (r *OPDSRouter) listCollectionsV2(c *gin.Context) {
+	collections, err := r.collections.ListCollections(c.Request.Context())
+	if err != nil {
+		r.logger.Error("failed to list collections", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/v2/collections/"
+	navigation := translateCollectionsToNavigation(collections, baseUrl)
+	feed := BuildFeedV2("Collections", baseUrl, navigation, nil, nil, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) listCollectionBooksV2(c *gin.Context, collectionID string) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	collection, err := r.collections.ViewCollection(c.Request.Context(), collectionID)
+	if err != nil {
+		r.logger.Error("failed to view collection", err)
+		c.JSON(http.StatusNotFound, gin.H{"message": "collection not found"})
+		return
+	}
+	filter := library.BookFilter{Collection: collection.ID}
+	books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, "title", "asc", page, 10)
+	if err != nil {
+		r.logger.Error("failed to list books by collection", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/v2/collections/" + url.PathEscape(collection.ID) + "/"
+	feed := BuildPaginatedFeedV2(collection.Name, baseUrl, books, r.readingProgress(c.Request.Context(), books.Books), page, 10, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) searchBooksV2(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	query := c.Query("query")
+	if query == "" {
+		query = c.Query("q")
+	}
+	if query == "" {
+		query = c.Param("searchTerms")
+	}
+	books, err := r.books.SearchBooks(c.Request.Context(), query, page, 10)
+	if err != nil {
+		r.logger.Error("failed to search books", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+		return
+	}
+	baseUrl := r.urlPrefix + "/opds/v2/search?" + url.Values{"query": {query}}.Encode()
+	feed := BuildPaginatedFeedV2("Search: "+query, baseUrl, books, r.readingProgress(c.Request.Context(), books.Books), page, 10, r.urlPrefix)
+	r.renderV2(c, feed)
+}
+
+func (r *OPDSRouter) listStatusV2(s statusShelf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil {
+			page = 1
+		}
+		filter := library.BookFilter{Status: s.status}
+		books, err := r.books.ListFilteredBooks(c.Request.Context(), filter, "last_activity", "desc", page, 10)
+		if err != nil {
+			r.logger.Error("failed to list books by status", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error", "code": 1001})
+			return
+		}
+		baseUrl := r.urlPrefix + "/opds/v2/" + s.path + "/"
+		feed := BuildPaginatedFeedV2(s.title, baseUrl, books, r.readingProgress(c.Request.Context(), books.Books), page, 10, r.urlPrefix)
+		r.renderV2(c, feed)
+	}
+}
@@ -1,9 +1,16 @@
 package web
 
 import (
+	"crypto/md5"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vanadium23/kompanion/internal/entity"
@@ -13,23 +20,86 @@ import (
 	"github.com/vanadium23/kompanion/pkg/logger"
 )
 
+// maxCoverUploadSize limits uploaded cover images.
+const maxCoverUploadSize = 20 * 1024 * 1024
+
 type booksRoutes struct {
-	urlPrefix string
-	shelf     library.Shelf
-	stats     stats.ReadingStats
-	progress  syncpkg.Progress
-	logger    logger.Interface
+	urlPrefix   string
+	shelf       library.Shelf
+	importer    *library.Importer
+	enricher    *library.Enricher
+	conversions *library.ConversionQueue
+	collections library.Collections
+	stats       stats.ReadingStats
+	progress    syncpkg.Progress
+	logger      logger.Interface
 }
 
-func newBooksRoutes(handler *gin.RouterGroup, urlPrefix string, shelf library.Shelf, stats stats.ReadingStats, progress syncpkg.Progress, l logger.Interface) {
-	r := &booksRoutes{urlPrefix: urlPrefix, shelf: shelf, stats: stats, progress: progress, logger: l}
+func newBooksRoutes(handler *gin.RouterGroup, urlPrefix string, shelf library.Shelf, enricher *library.Enricher, conversions *library.ConversionQueue, collections library.Collections, stats stats.ReadingStats, progress syncpkg.Progress, l logger.Interface) {
+	r := &booksRoutes{urlPrefix: urlPrefix, shelf: shelf, importer: library.NewImporter(shelf, l), enricher: enricher, conversions: conversions, collections: collections, stats: stats, progress: progress, logger: l}
 
 	handler.GET("/", r.listBooks)
 	handler.POST("/upload", r.uploadBook)
+	handler.POST("/import", r.importBooks)
+	handler.GET("/trash", r.listTrash)
+	handler.GET("/duplicates", r.listDuplicates)
+	handler.POST("/duplicates", r.mergeDuplicates)
 	handler.GET("/:bookID", r.viewBook)
 	handler.POST("/:bookID", r.updateBookMetadata)
 	handler.GET("/:bookID/download", r.downloadBook)
 	handler.GET("/:bookID/cover", r.viewBookCover)
+	handler.POST("/:bookID/cover", r.uploadBookCover)
+	handler.POST("/:bookID/cover/extract", r.reextractBookCover)
+	handler.GET("/:bookID/metadata", r.fetchBookMetadata)
+	handler.POST("/:bookID/metadata", r.applyBookMetadata)
+	handler.POST("/:bookID/replace", r.replaceBookFile)
+	handler.POST("/:bookID/formats", r.addBookFormat)
+	handler.GET("/:bookID/formats/:documentID/download", r.downloadBookFormat)
+	handler.POST("/:bookID/formats/:documentID/delete", r.deleteBookFormat)
+	handler.POST("/:bookID/convert", r.convertBook)
+	handler.POST("/:bookID/delete", r.deleteBook)
+	handler.POST("/:bookID/restore", r.restoreBook)
+}
+
+// bookSort is an order of books selectable on the list page.
+type bookSort struct {
+	Value     string
+	Label     string
+	sortBy    string
+	sortOrder string
+}
+
+var bookSorts = []bookSort{
+	{"newest", "Newest", "created_at", "desc"},
+	{"oldest", "Oldest", "created_at", "asc"},
+	{"title", "Title", "title", "asc"},
+	{"author", "Author", "author", "asc"},
+	{"year", "Year", "year", "desc"},
+	{"activity", "Recently read", "last_activity", "desc"},
+	{"relevance", "Relevance", "relevance", "desc"},
+}
+
+// findBookSort returns sort by its value, search is ordered by relevance by default.
+func findBookSort(value, query string) bookSort {
+	if value == "" && query != "" {
+		value = "relevance"
+	}
+	for _, s := range bookSorts {
+		if s.Value == value {
+			return s
+		}
+	}
+	return bookSorts[0]
+}
+
+// knownCollection returns id if it is one of collections, unknown id is ignored.
+func knownCollection(collections []entity.Collection, id string) string {
+	for _, collection := range collections {
+		if collection.ID == id {
+			return id
+		}
+	}
+	return ""
 }
 
 func (r *booksRoutes) listBooks(c *gin.Context) {
@@ -41,7 +111,34 @@ func (r *booksRoutes) listBooks(c *gin.Context) {
 		}
 	}
 
-	books, err := r.shelf.ListBooks(c.Request.Context(), "created_at", "desc", page, perPage)
+	filter := library.BookFilter{
+		Query:      strings.TrimSpace(c.Query("q")),
+		Author:     c.Query("author"),
+		Language:   c.Query("language"),
+		Format:     c.Query("format"),
+		Status:     c.Query("status"),
+		Collection: c.Query("collection"),
+	}
+	sort := findBookSort(c.Query("sort"), filter.Query)
+
+	collections, err := r.collections.ListCollections(c.Request.Context())
+	if err != nil {
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	filter.Collection = knownCollection(collections, filter.Collection)
+	authors, err := r.shelf.ListGroups(c.Request.Context(), library.GroupByAuthor)
+	if err != nil {
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	languages, err := r.shelf.ListGroups(c.Request.Context(), library.GroupByLanguage)
+	if err != nil {
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+
+	books, err := r.shelf.ListFilteredBooks(c.Request.Context(), filter, sort.sortBy, sort.sortOrder, page, perPage)
 	if err != nil {
 		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
 		return
@@ -52,22 +149,65 @@ func (r *booksRoutes) listBooks(c *gin.Context) {
 		entity.Book
 		Progress int
 	}
+	documentIDs := make([]string, len(books.Books))
+	for i, book := range books.Books {
+		documentIDs[i] = book.DocumentID
+	}
+	progress, err := r.progress.FetchMany(c.Request.Context(), documentIDs)
+	if err != nil {
+		r.logger.Error(err, "failed to fetch progress for books")
+	}
 	booksWithProgress := make([]BookWithProgress, len(books.Books))
 	for i, book := range books.Books {
-		progress, err := r.progress.Fetch(c.Request.Context(), book.DocumentID)
-		if err != nil {
-			r.logger.Error(err, "failed to fetch progress for book %s", book.ID)
-			progress = entity.Progress{}
-		}
 		booksWithProgress[i] = BookWithProgress{
 			Book:     book,
-			Progress: int(progress.Percentage * 100),
+			Progress: int(progress[book.DocumentID].Percentage * 100),
 		}
 	}
 
+	// links keep selected filters and sort
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"q":          filter.Query,
+		"author":     filter.Author,
+		"language":   filter.Language,
+		"format":     filter.Format,
+		"status":     filter.Status,
+		"collection": filter.Collection,
+		"sort":       c.Query("sort"),
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	type collectionChip struct {
+		entity.Collection
+		Query  template.URL
+		Active bool
+	}
+	chips := make([]collectionChip, len(collections))
+	for i, collection := range collections {
+		chipQuery := maps.Clone(query)
+		chipQuery.Set("collection", collection.ID)
+		chips[i] = collectionChip{collection, template.URL(chipQuery.Encode()), collection.ID == filter.Collection}
+	}
+	allQuery := maps.Clone(query)
+	allQuery.Del("collection")
+
 	c.HTML(200, "books", passStandartContext(c, gin.H{
-		"urlPrefix": r.urlPrefix,
-		"books":     booksWithProgress,
+		"urlPrefix":   r.urlPrefix,
+		"books":       booksWithProgress,
+		"total":       books.TotalCount(),
+		"filter":      filter,
+		"sort":        sort.Value,
+		"sorts":       bookSorts,
+		"authors":     authors,
+		"languages":   languages,
+		"formats":     library.Formats,
+		"statuses":    []string{library.StatusReading, library.StatusFinished, library.StatusUnread},
+		"collections": chips,
+		"allQuery":    template.URL(allQuery.Encode()),
+		"query":       template.URL(query.Encode()),
 		"pagination": gin.H{
 			"currentPage": page,
 			"perPage":     perPage,
@@ -101,7 +241,11 @@ func (r *booksRoutes) uploadBook(c *gin.Context) {
 	filepath := tempFile.Name()
 	defer os.Remove(filepath)
 	defer tempFile.Close()
-	c.SaveUploadedFile(uploadedBookFile, filepath)
+	if err = c.SaveUploadedFile(uploadedBookFile, filepath); err != nil {
+		r.logger.Error(err, "http - v1 - shelf - putBook")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
 
 	book, err := r.shelf.StoreBook(c.Request.Context(), tempFile, uploadedBookFile.Filename)
 	if err != nil && err != entity.ErrBookAlreadyExists {
@@ -112,6 +256,38 @@ func (r *booksRoutes) uploadBook(c *gin.Context) {
 	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
 }
 
+// importBooks stores several uploaded files and ZIP archives at once.
+func (r *booksRoutes) importBooks(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["books"]) == 0 {
+		r.logger.Error(err, "http - web - books - importBooks")
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book files are required"}))
+		return
+	}
+
+	var report library.ImportReport
+	for _, uploadedFile := range form.File["books"] {
+		tempFile, err := os.CreateTemp("", "")
+		if err != nil {
+			r.logger.Error(err, "http - web - books - importBooks")
+			c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+			return
+		}
+		tempFile.Close()
+		if err = c.SaveUploadedFile(uploadedFile, tempFile.Name()); err == nil {
+			report.Results = append(report.Results, r.importer.Import(c.Request.Context(), tempFile.Name(), uploadedFile.Filename)...)
+		} else {
+			report.Results = append(report.Results, library.ImportResult{Name: uploadedFile.Filename, Status: library.ImportFailed, Err: err})
+		}
+		os.Remove(tempFile.Name())
+	}
+
+	c.HTML(200, "import", passStandartContext(c, gin.H{
+		"urlPrefix": r.urlPrefix,
+		"report":    report,
+	}))
+}
+
 func (r *booksRoutes) downloadBook(c *gin.Context) {
 	bookID := c.Param("bookID")
 
@@ -142,10 +318,25 @@ func (r *booksRoutes) viewBook(c *gin.Context) {
 		bookStats = &stats.BookStats{} // Use empty stats in case of error
 	}
 
+	collections, err := r.collections.ListCollections(c.Request.Context())
+	if err != nil {
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	bookCollections, err := r.collections.ListBookCollections(c.Request.Context(), bookID)
+	if err != nil {
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+
 	c.HTML(200, "book", passStandartContext(c, gin.H{
-		"urlPrefix": r.urlPrefix,
-		"book":      book,
-		"stats":     bookStats,
+		"urlPrefix":       r.urlPrefix,
+		"book":            book,
+		"stats":           bookStats,
+		"collections":     collections,
+		"bookCollections": bookCollections,
+		"conversions":     r.conversions.Targets(book),
+		"converting":      r.conversions.Pending(book.ID),
 	}))
 }
 
@@ -159,6 +350,7 @@ func (r *booksRoutes) updateBookMetadata(c *gin.Context) {
 		c.JSON(400, passStandartContext(c, gin.H{"message": "invalid request"}))
 		return
 	}
+	metadata.Subjects = splitSubjects(c.PostForm("subjects"))
 
 	book, err := r.shelf.UpdateBookMetadata(c.Request.Context(), bookID, metadata)
 	if err != nil {
@@ -168,10 +360,240 @@ func (r *booksRoutes) updateBookMetadata(c *gin.Context) {
 		return
 	}
 
-	// TODO: why not redirect?
-	c.HTML(200, "book", passStandartContext(c, gin.H{"urlPrefix": r.urlPrefix, "book": book}))
+	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
+}
+
+// fetchBookMetadata shows differences between the book and online catalogs.
+func (r *booksRoutes) fetchBookMetadata(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	book, suggestions, err := r.enricher.Suggest(c.Request.Context(), bookID)
+	if err != nil {
+		r.logger.Error(err, "http - web - books - fetchBookMetadata")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+
+	c.HTML(200, "metadata", passStandartContext(c, gin.H{
+		"urlPrefix":   r.urlPrefix,
+		"book":        book,
+		"suggestions": suggestions,
+	}))
+}
+
+func (r *booksRoutes) applyBookMetadata(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	provider := c.PostForm("provider")
+	fields := c.PostFormArray("fields")
+	if provider == "" || len(fields) == 0 {
+		c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+		return
+	}
+
+	book, err := r.enricher.Apply(c.Request.Context(), bookID, provider, fields)
+	if err != nil {
+		r.logger.Error(err, "http - web - books - applyBookMetadata")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
+}
+
+func (r *booksRoutes) replaceBookFile(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	uploadedBookFile, err := c.FormFile("book")
+	if err != nil {
+		r.logger.Error(err, "http - web - books - replaceBookFile")
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book file is required"}))
+		return
+	}
+
+	tempFile, err := os.CreateTemp("", "")
+	if err != nil {
+		r.logger.Error(err, "http - web - books - replaceBookFile")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+	if err = c.SaveUploadedFile(uploadedBookFile, tempFile.Name()); err != nil {
+		r.logger.Error(err, "http - web - books - replaceBookFile")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+
+	book, err := r.shelf.ReplaceBookFile(c.Request.Context(), bookID, tempFile, uploadedBookFile.Filename)
+	if errors.Is(err, entity.ErrUnsupportedFormat) {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "unsupported book format"}))
+		return
+	}
+	if err != nil && !errors.Is(err, entity.ErrBookAlreadyExists) {
+		r.logger.Error(err, "http - web - books - replaceBookFile")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
+}
+
+func (r *booksRoutes) addBookFormat(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	uploadedBookFile, err := c.FormFile("book")
+	if err != nil {
+		r.logger.Error(err, "http - web - books - addBookFormat")
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book file is required"}))
+		return
+	}
+
+	tempFile, err := os.CreateTemp("", "")
+	if err != nil {
+		r.logger.Error(err, "http - web - books - addBookFormat")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+	if err = c.SaveUploadedFile(uploadedBookFile, tempFile.Name()); err != nil {
+		r.logger.Error(err, "http - web - books - addBookFormat")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+
+	book, err := r.shelf.AddBookFormat(c.Request.Context(), bookID, tempFile, uploadedBookFile.Filename)
+	if errors.Is(err, entity.ErrUnsupportedFormat) {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "unsupported book format"}))
+		return
+	}
+	if err != nil && err != entity.ErrBookAlreadyExists {
+		r.logger.Error(err, "http - web - books - addBookFormat")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
+}
+
+// convertBook queues conversion, converted file appears on the book page when ready.
+func (r *booksRoutes) convertBook(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	err := r.conversions.Enqueue(bookID, c.PostForm("format"))
+	if errors.Is(err, library.ErrNoConverter) {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "unknown format"}))
+		return
+	}
+	if errors.Is(err, library.ErrConversionFull) {
+		c.JSON(503, passStandartContext(c, gin.H{"message": "too many conversions, try later"}))
+		return
+	}
+	if err != nil {
+		r.logger.Error(err, "http - web - books - convertBook")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+func (r *booksRoutes) downloadBookFormat(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	book, file, err := r.shelf.DownloadBookFormat(c.Request.Context(), bookID, c.Param("documentID"))
+	if err != nil {
+		r.logger.Error(err, "http - web - books - downloadBookFormat")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", "attachment; filename="+book.Filename())
+	c.Header("Content-Type", "application/octet-stream")
+	c.File(file.Name())
+}
+
+func (r *booksRoutes) deleteBookFormat(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	err := r.shelf.DeleteBookFormat(c.Request.Context(), bookID, c.Param("documentID"))
+	if err != nil {
+		r.logger.Error(err, "http - web - books - deleteBookFormat")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+func (r *booksRoutes) deleteBook(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	err := r.shelf.DeleteBook(c.Request.Context(), bookID)
+	if err != nil {
+		r.logger.Error(err, "http - web - books - deleteBook")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books")
+}
+
+func (r *booksRoutes) listTrash(c *gin.Context) {
+	books, err := r.shelf.ListTrash(c.Request.Context())
+	if err != nil {
+		r.logger.Error(err, "http - web - books - listTrash")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+
+	c.HTML(200, "trash", passStandartContext(c, gin.H{
+		"urlPrefix": r.urlPrefix,
+		"books":     books,
+	}))
+}
+
+func (r *booksRoutes) listDuplicates(c *gin.Context) {
+	groups, err := r.shelf.FindDuplicates(c.Request.Context())
+	if err != nil {
+		r.logger.Error(err, "http - web - books - listDuplicates")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+
+	c.HTML(200, "duplicates", passStandartContext(c, gin.H{
+		"urlPrefix": r.urlPrefix,
+		"groups":    groups,
+	}))
 }
 
+func (r *booksRoutes) mergeDuplicates(c *gin.Context) {
+	bookID := c.PostForm("book_id")
+	if bookID == "" {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book to keep is required"}))
+		return
+	}
+
+	book, err := r.shelf.MergeBooks(c.Request.Context(), bookID, c.PostFormArray("duplicates"))
+	if err != nil {
+		r.logger.Error(err, "http - web - books - mergeDuplicates")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+book.ID)
+}
+
+func (r *booksRoutes) restoreBook(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	err := r.shelf.RestoreBook(c.Request.Context(), bookID)
+	if err != nil {
+		r.logger.Error(err, "http - web - books - restoreBook")
+		c.HTML(500, "error", passStandartContext(c, gin.H{"error": err.Error()}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+// viewBookCover serves cover or its thumbnail with ?size=small|medium|large,
+// books without cover get generated one. ETag changes with the cover,
+// so browsers revalidate instead of refetching.
 func (r *booksRoutes) viewBookCover(c *gin.Context) {
 	bookID := c.Param("bookID")
 
@@ -181,28 +603,98 @@ func (r *booksRoutes) viewBookCover(c *gin.Context) {
 		return
 	}
 
-	cover, err := r.shelf.ViewCover(c.Request.Context(), bookID)
+	size := c.Query("size")
+	width, thumbnail := library.CoverSizes[size]
+	// title and author are part of generated cover
+	etag := fmt.Sprintf(`"%x"`, md5.Sum([]byte(book.CoverPath+"|"+size+"|"+book.Title+"|"+book.Author)))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(304)
+		return
+	}
+
+	var cover *os.File
+	if thumbnail {
+		cover, err = r.shelf.ViewCoverThumbnail(c.Request.Context(), bookID, width)
+	} else {
+		cover, err = r.shelf.ViewCover(c.Request.Context(), bookID)
+	}
+
+	if err != nil {
+		r.logger.Error(err, "http - web - books - viewBookCover")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	defer cover.Close()
+
+	c.File(cover.Name())
+}
+
+func (r *booksRoutes) uploadBookCover(c *gin.Context) {
+	bookID := c.Param("bookID")
 
+	uploadedCover, err := c.FormFile("cover")
 	if err != nil {
-		width := 600
-		height := 800
-		backgroundColor := "#6496FA" // Цвет фона (голубой)
-		textColor := "white"         // Цвет текста
-		title := book.Title
-		subtitle := book.Author
-		fontSizeTitle := 48
-		fontSizeSubtitle := 24
+		r.logger.Error(err, "http - web - books - uploadBookCover")
+		c.JSON(400, passStandartContext(c, gin.H{"message": "cover file is required"}))
+		return
+	}
+	coverFile, err := uploadedCover.Open()
+	if err != nil {
+		r.logger.Error(err, "http - web - books - uploadBookCover")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	defer coverFile.Close()
+	// one byte over the limit tells about too large cover
+	cover, err := io.ReadAll(io.LimitReader(coverFile, maxCoverUploadSize+1))
+	if err != nil {
+		r.logger.Error(err, "http - web - books - uploadBookCover")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	if len(cover) > maxCoverUploadSize {
+		c.JSON(413, passStandartContext(c, gin.H{"message": "cover must be smaller than 20 MB"}))
+		return
+	}
 
-		svgContent := fmt.Sprintf(`
-		<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">
-			<rect width="100%%" height="100%%" fill="%s" />
-			<text x="50%%" y="40%%" font-family="Arial" font-size="%d" fill="%s" text-anchor="middle">%s</text>
-			<text x="50%%" y="55%%" font-family="Arial" font-size="%d" fill="%s" text-anchor="middle">%s</text>
-		</svg>
-		`, width, height, backgroundColor, fontSizeTitle, textColor, title, fontSizeSubtitle, textColor, subtitle)
+	_, err = r.shelf.UpdateCover(c.Request.Context(), bookID, cover)
+	if errors.Is(err, entity.ErrInvalidCover) {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "cover must be JPEG, PNG or WebP image"}))
+		return
+	}
+	if err != nil {
+		r.logger.Error(err, "http - web - books - uploadBookCover")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+func (r *booksRoutes) reextractBookCover(c *gin.Context) {
+	bookID := c.Param("bookID")
 
-		c.Data(200, "image/svg+xml", []byte(svgContent))
+	_, err := r.shelf.ReextractCover(c.Request.Context(), bookID)
+	if errors.Is(err, entity.ErrCoverNotFound) || errors.Is(err, entity.ErrInvalidCover) {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book file has no usable cover"}))
 		return
 	}
-	c.File(cover.Name())
+	if err != nil {
+		r.logger.Error(err, "http - web - books - reextractBookCover")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+// splitSubjects parses comma separated subjects of the edit form.
+func splitSubjects(value string) []string {
+	var subjects []string
+	for _, subject := range strings.Split(value, ",") {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects
 }
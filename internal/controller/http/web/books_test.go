@@ -0,0 +1,21 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+)
+
+func TestKnownCollection(t *testing.T) {
+	collections := []entity.Collection{
+		{ID: "0b7d4c9e-2f6a-4f43-9a4e-5f1d2c3b4a59", Name: "Favorites"},
+	}
+
+	assert.Equal(t, "0b7d4c9e-2f6a-4f43-9a4e-5f1d2c3b4a59", knownCollection(collections, "0b7d4c9e-2f6a-4f43-9a4e-5f1d2c3b4a59"))
+	// not uuid must not reach the database
+	assert.Equal(t, "", knownCollection(collections, "not-a-uuid"))
+	assert.Equal(t, "", knownCollection(collections, "5c2e8a1f-3d4b-4e6f-8a9b-0c1d2e3f4a5b"))
+	assert.Equal(t, "", knownCollection(nil, ""))
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+type collectionRoutes struct {
+	urlPrefix   string
+	collections library.Collections
+	logger      logger.Interface
+}
+
+func newCollectionRoutes(handler *gin.RouterGroup, urlPrefix string, collections library.Collections, l logger.Interface) {
+	r := &collectionRoutes{urlPrefix: urlPrefix, collections: collections, logger: l}
+
+	handler.GET("/", r.listCollections)
+	handler.POST("/", r.createCollection)
+	handler.POST("/books", r.addBook)
+	handler.POST("/:collectionID", r.renameCollection)
+	handler.POST("/:collectionID/delete", r.deleteCollection)
+	handler.POST("/:collectionID/books/:bookID/delete", r.removeBook)
+}
+
+func (r *collectionRoutes) listCollections(c *gin.Context) {
+	r.renderCollections(c, 200, "")
+}
+
+func (r *collectionRoutes) renderCollections(c *gin.Context, code int, message string) {
+	collections, err := r.collections.ListCollections(c.Request.Context())
+	if err != nil {
+		r.logger.Error(err, "http - web - collections - listCollections")
+		c.HTML(500, "collections", passStandartContext(c, gin.H{
+			"urlPrefix": r.urlPrefix,
+			"error":     "Failed to load collections",
+		}))
+		return
+	}
+
+	c.HTML(code, "collections", passStandartContext(c, gin.H{
+		"urlPrefix":   r.urlPrefix,
+		"collections": collections,
+		"error":       message,
+	}))
+}
+
+func (r *collectionRoutes) createCollection(c *gin.Context) {
+	_, err := r.collections.CreateCollection(c.Request.Context(), c.PostForm("name"))
+	if err != nil {
+		r.handleError(c, err, "http - web - collections - createCollection")
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/collections")
+}
+
+func (r *collectionRoutes) renameCollection(c *gin.Context) {
+	_, err := r.collections.RenameCollection(c.Request.Context(), c.Param("collectionID"), c.PostForm("name"))
+	if err != nil {
+		r.handleError(c, err, "http - web - collections - renameCollection")
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/collections")
+}
+
+func (r *collectionRoutes) deleteCollection(c *gin.Context) {
+	err := r.collections.DeleteCollection(c.Request.Context(), c.Param("collectionID"))
+	if err != nil {
+		r.handleError(c, err, "http - web - collections - deleteCollection")
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/collections")
+}
+
+// addBook is posted from the book page, collection and book ids come in the form.
+func (r *collectionRoutes) addBook(c *gin.Context) {
+	bookID := c.PostForm("book_id")
+	if bookID == "" {
+		c.JSON(400, passStandartContext(c, gin.H{"message": "book is required"}))
+		return
+	}
+	collection, err := r.collections.ViewCollection(c.Request.Context(), c.PostForm("collection"))
+	if err != nil {
+		r.logger.Error(err, "http - web - collections - addBook")
+		c.JSON(400, passStandartContext(c, gin.H{"message": "collection not found"}))
+		return
+	}
+
+	err = r.collections.AddBook(c.Request.Context(), collection.ID, bookID)
+	if err != nil {
+		r.logger.Error(err, "http - web - collections - addBook")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+func (r *collectionRoutes) removeBook(c *gin.Context) {
+	bookID := c.Param("bookID")
+
+	err := r.collections.RemoveBook(c.Request.Context(), c.Param("collectionID"), bookID)
+	if err != nil {
+		r.logger.Error(err, "http - web - collections - removeBook")
+		c.JSON(500, passStandartContext(c, gin.H{"message": "internal server error"}))
+		return
+	}
+	c.Redirect(302, r.urlPrefix+"/books/"+bookID)
+}
+
+func (r *collectionRoutes) handleError(c *gin.Context, err error, method string) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidCollectionName):
+		r.renderCollections(c, 400, "Collection name is required")
+	case errors.Is(err, entity.ErrCollectionAlreadyExists):
+		r.renderCollections(c, 400, "Collection with this name already exists")
+	default:
+		r.logger.Error(err, method)
+		r.renderCollections(c, 500, "Failed to save collection")
+	}
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -29,6 +30,9 @@ func NewRouter(
 	a auth.AuthInterface,
 	p sync.Progress,
 	shelf library.Shelf,
+	enricher *library.Enricher,
+	conversions *library.ConversionQueue,
+	collections library.Collections,
 	stats stats.ReadingStats,
 	version string,
 ) {
@@ -60,6 +64,10 @@ func NewRouter(
 			}
 			return template.JS(b)
 		},
+		"join": strings.Join,
+		"coverURL": func(cover []byte) template.URL {
+			return template.URL("data:" + http.DetectContentType(cover) + ";base64," + base64.StdEncoding.EncodeToString(cover))
+		},
 		"subtract": func(a, b int) int {
 			return a - b
 		},
@@ -88,7 +96,12 @@ func NewRouter(
 	// Product pages
 	bookGroup := handler.Group("/books")
 	bookGroup.Use(authMiddleware(a, urlPrefix))
-	newBooksRoutes(bookGroup, urlPrefix, shelf, stats, p, l)
+	newBooksRoutes(bookGroup, urlPrefix, shelf, enricher, conversions, collections, stats, p, l)
+
+	// Collections
+	collectionGroup := handler.Group("/collections")
+	collectionGroup.Use(authMiddleware(a, urlPrefix))
+	newCollectionRoutes(collectionGroup, urlPrefix, collections, l)
 
 	// Stats pages
 	statsGroup := handler.Group("/stats")
@@ -6,31 +6,99 @@ import (
 	"time"
 )
 
-var ErrBookAlreadyExists = errors.New("Book already exists")
+var (
+	ErrBookAlreadyExists = errors.New("Book already exists")
+	ErrUnsupportedFormat = errors.New("Unsupported book format")
+	ErrInvalidCover      = errors.New("Invalid cover image")
+	ErrCoverNotFound     = errors.New("Cover not found")
+)
 
 // Book represents a book entity in the database.
 type Book struct {
-	ID         string    // unique identifier for the book
-	Title      string    `form:"title"`     // title of the book
-	Author     string    `form:"author"`    // author of the book
-	Publisher  string    `form:"publisher"` // publisher of the book
-	Year       int       `form:"year"`      // year of publication
-	CreatedAt  time.Time // timestamp of when the book was created
-	UpdatedAt  time.Time // timestamp of when the book was last updated
-	ISBN       string    // ISBN of the book
-	DocumentID string    // md5 hash for file content
-	FilePath   string    // path to the book file
-	Format     string    // format of the book file
-	CoverPath  string    // path to the cover image
+	ID          string       // unique identifier for the book
+	Title       string       `form:"title"`     // title of the book
+	Author      string       `form:"author"`    // author of the book
+	Publisher   string       `form:"publisher"` // publisher of the book
+	Year        int          `form:"year"`      // year of publication
+	CreatedAt   time.Time    // timestamp of when the book was created
+	UpdatedAt   time.Time    // timestamp of when the book was last updated
+	ISBN        string       // ISBN of the book
+	DocumentID  string       // md5 hash for file content
+	FilePath    string       // path to the book file
+	Format      string       // format of the book file
+	CoverPath   string       // path to the cover image
+	Series      string       `form:"series"`       // series the book belongs to
+	SeriesIndex float64      `form:"series_index"` // position of the book in series
+	Language    string       `form:"language"`     // language of the book
+	Description string       `form:"description"`  // annotation of the book
+	Subjects    []string     // subjects or tags of the book
+	Pages       int          // number of pages, if format has them
+	DeletedAt   time.Time    // timestamp of when the book was moved to trash
+	Formats     []BookFormat // additional files of the book
+}
+
+// IsDeleted reports whether the book is in trash.
+func (b Book) IsDeleted() bool {
+	return !b.DeletedAt.IsZero()
+}
+
+// BookFormat is an additional file of the book, e.g. PDF of the book stored as EPUB.
+type BookFormat struct {
+	BookID     string    // book, which file belongs to
+	DocumentID string    // md5 hash for file content, used for sync
+	FilePath   string    // path to the file
+	Format     string    // format of the file
+	Pages      int       // number of pages, if format has them
+	CreatedAt  time.Time // timestamp of when the file was added
+}
+
+// MimeType returns MIME type of the file.
+func (f BookFormat) MimeType() string {
+	return mimeType(f.Format)
+}
+
+// FileFormat returns format of the book file by its extension.
+func (b Book) FileFormat() string {
+	return b.extension()
+}
+
+// Files returns the main file of the book followed by additional formats.
+func (b Book) Files() []BookFormat {
+	main := BookFormat{
+		BookID:     b.ID,
+		DocumentID: b.DocumentID,
+		FilePath:   b.FilePath,
+		Format:     b.extension(),
+		Pages:      b.Pages,
+		CreatedAt:  b.CreatedAt,
+	}
+	return append([]BookFormat{main}, b.Formats...)
+}
+
+// WithFile returns the book, which main file is the given format,
+// e.g. to name downloaded file.
+func (b Book) WithFile(f BookFormat) Book {
+	b.DocumentID = f.DocumentID
+	b.FilePath = f.FilePath
+	b.Format = f.Format
+	b.Pages = f.Pages
+	return b
 }
 
 func (b Book) extension() string {
+	// zipped FB2 keeps both extensions, so readers know what is inside
+	if strings.HasSuffix(b.FilePath, ".fb2.zip") {
+		return "fb2.zip"
+	}
+	if strings.HasSuffix(b.FilePath, ".kepub.epub") {
+		return "kepub"
+	}
 	tmp := strings.Split(b.FilePath, ".")
 	return tmp[len(tmp)-1]
 }
 
 func (b Book) Filename() string {
-	basename := b.ID + "." + b.extension()
+	basename := b.ID + "." + FileExtension(b.extension())
 	if len(b.Author) == 0 {
 		return b.Title + " -- " + basename
 	}
@@ -38,15 +106,50 @@ func (b Book) Filename() string {
 }
 
 func (b Book) MimeType() string {
-	switch b.extension() {
+	return mimeType(b.extension())
+}
+
+// FileExtension returns extension of the file in the format,
+// KEPUB keeps .epub, as Kobo readers expect.
+func FileExtension(format string) string {
+	if format == "kepub" {
+		return "kepub.epub"
+	}
+	return format
+}
+
+func mimeType(format string) string {
+	switch format {
 	case "epub":
 		return "application/epub+zip"
+	case "kepub":
+		return "application/kepub+zip"
 	case "pdf":
 		return "application/pdf"
 	case "mobi":
 		return "application/x-mobipocket-ebook"
+	case "azw3":
+		return "application/vnd.amazon.ebook"
 	case "fb2":
 		return "application/fb2"
+	case "fb2.zip":
+		return "application/fb2+zip"
+	case "cbz":
+		return "application/vnd.comicbook+zip"
+	case "cbr":
+		return "application/vnd.comicbook-rar"
+	case "djvu":
+		return "image/vnd.djvu"
+	case "txt":
+		return "text/plain"
+	case "md":
+		return "text/markdown"
+	case "html":
+		return "text/html"
+	case "rtf":
+		return "application/rtf"
+	case "docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
 	default:
 		return ""
 	}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrCollectionAlreadyExists = errors.New("Collection already exists")
+	ErrInvalidCollectionName   = errors.New("Collection name is empty")
+)
+
+// Collection is a user defined shelf or tag of books.
+type Collection struct {
+	ID        string    // unique identifier for the collection
+	Name      string    `form:"name"` // name of the collection, unique
+	CreatedAt time.Time // timestamp of when the collection was created
+	UpdatedAt time.Time // timestamp of when the collection was last updated
+	BookCount int       // number of books in the collection, excluding trash
+}
@@ -4,11 +4,28 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/vanadium23/kompanion/internal/entity"
 	"github.com/vanadium23/kompanion/pkg/postgres"
 )
 
+// searchCondition matches the query as full-text terms, as a substring
+// or as a fuzzy word (pg_trgm) against the generated search_text column.
+// Wildcards of the query are escaped, so "100%" is matched literally.
+const searchCondition = `(
+	to_tsvector('simple', search_text) @@ plainto_tsquery('simple', $1)
+	OR search_text ILIKE '%' || replace(replace(replace($1, '\', '\\'), '%', '\%'), '_', '\_') || '%' ESCAPE '\'
+	OR $1 <% search_text
+)`
+
+// bookColumns is a list of columns scanned by scanBook.
+const bookColumns = `id, title, author, publisher, year, created_at, updated_at, isbn,
+	storage_file_path, koreader_partial_md5, storage_cover_path,
+	coalesce(series, ''), coalesce(language, ''), coalesce(summary, ''), deleted_at,
+	coalesce(series_index, 0), coalesce(subjects, '{}'), coalesce(pages, 0)`
+
 // BookDatabaseRepo -.
 type BookDatabaseRepo struct {
 	*postgres.Postgres
@@ -22,13 +39,14 @@ func NewBookDatabaseRepo(pg *postgres.Postgres) *BookDatabaseRepo {
 // Store -. only insert in database
 func (bdr *BookDatabaseRepo) Store(ctx context.Context, book entity.Book) error {
 	sql := `
-		INSERT INTO library_book (id, title, author, publisher, year, created_at, updated_at, isbn, storage_file_path, koreader_partial_md5, storage_cover_path)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO library_book (id, title, author, publisher, year, created_at, updated_at, isbn, storage_file_path, koreader_partial_md5, storage_cover_path, series, language, summary, series_index, subjects, pages)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 	args := []interface{}{
 		book.ID, book.Title, book.Author, book.Publisher, book.Year,
 		book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath,
-		book.DocumentID, book.CoverPath,
+		book.DocumentID, book.CoverPath, book.Series, book.Language,
+		book.Description, book.SeriesIndex, book.Subjects, book.Pages,
 	}
 
 	_, err := bdr.Pool.Exec(ctx, sql, args...)
@@ -44,22 +62,7 @@ func (bdr *BookDatabaseRepo) Store(ctx context.Context, book entity.Book) error
 
 // Update -. only update in database
 func (bdr *BookDatabaseRepo) Update(ctx context.Context, book entity.Book) error {
-	sql := `
-		UPDATE library_book
-		SET title = $1,
-			author = $2,
-			publisher = $3,
-			year = $4,
-			updated_at = $5,
-			isbn = $6
-		WHERE id = $7
-	`
-	args := []interface{}{
-		book.Title, book.Author, book.Publisher, book.Year,
-		book.UpdatedAt, book.ISBN, book.ID,
-	}
-
-	rows, err := bdr.Pool.Exec(ctx, sql, args...)
+	rows, err := bdr.Pool.Exec(ctx, updateBookSQL, updateBookArgs(book)...)
 	if err != nil {
 		return fmt.Errorf("BookDatabaseRepo - Update - r.Pool.Exec: %w", err)
 	}
@@ -69,6 +72,30 @@ func (bdr *BookDatabaseRepo) Update(ctx context.Context, book entity.Book) error
 	return nil
 }
 
+const updateBookSQL = `
+	UPDATE library_book
+	SET title = $1,
+		author = $2,
+		publisher = $3,
+		year = $4,
+		updated_at = $5,
+		isbn = $6,
+		series = $7,
+		language = $8,
+		summary = $9,
+		series_index = $10,
+		subjects = $11
+	WHERE id = $12
+`
+
+func updateBookArgs(book entity.Book) []interface{} {
+	return []interface{}{
+		book.Title, book.Author, book.Publisher, book.Year,
+		book.UpdatedAt, book.ISBN, book.Series, book.Language,
+		book.Description, book.SeriesIndex, book.Subjects, book.ID,
+	}
+}
+
 // List -. only select from database
 func (bdr *BookDatabaseRepo) List(ctx context.Context,
 	sortBy, sortOrder string,
@@ -86,22 +113,17 @@ func (bdr *BookDatabaseRepo) List(ctx context.Context,
 		sortBy = "created_at"
 	}
 
-	if page <= 0 {
-		page = 1
-	}
-	if perPage <= 0 || perPage > 100 {
-		perPage = 25
-	}
+	page, perPage = normalizePagination(page, perPage)
 
 	// Use limit and offset for pagination, because we don't have a lot of books
 	// (yes, it's not the best way to do pagination)
 	sql := fmt.Sprintf(`
-		SELECT 
-			id, title, author, publisher, year, created_at, updated_at, isbn, storage_file_path, koreader_partial_md5, storage_cover_path
+		SELECT %s
 		FROM library_book
+		WHERE deleted_at IS NULL
 		ORDER BY %s %s
 		LIMIT %d OFFSET %d
-	`, sortBy, sortOrder, perPage, (page-1)*perPage)
+	`, bookColumns, sortBy, sortOrder, perPage, (page-1)*perPage)
 
 	rows, err := bdr.Pool.Query(ctx, sql)
 	if err != nil {
@@ -111,8 +133,7 @@ func (bdr *BookDatabaseRepo) List(ctx context.Context,
 
 	books := make([]entity.Book, 0)
 	for rows.Next() {
-		var book entity.Book
-		err = rows.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.Year, &book.CreatedAt, &book.UpdatedAt, &book.ISBN, &book.FilePath, &book.DocumentID, &book.CoverPath)
+		book, err := scanBook(rows)
 		if err != nil {
 			return nil, fmt.Errorf("BookDatabaseRepo - List - rows.Scan: %w", err)
 		}
@@ -122,18 +143,63 @@ func (bdr *BookDatabaseRepo) List(ctx context.Context,
 	return books, nil
 }
 
+// Search -. full-text and trigram search over title, author, publisher, series and isbn
+func (bdr *BookDatabaseRepo) Search(ctx context.Context,
+	query string,
+	page, perPage int,
+) ([]entity.Book, error) {
+	page, perPage = normalizePagination(page, perPage)
+
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM library_book
+		WHERE deleted_at IS NULL AND %s
+		ORDER BY
+			ts_rank(to_tsvector('simple', search_text), plainto_tsquery('simple', $1)) DESC,
+			word_similarity($1, search_text) DESC,
+			created_at DESC
+		LIMIT %d OFFSET %d
+	`, bookColumns, searchCondition, perPage, (page-1)*perPage)
+
+	rows, err := bdr.Pool.Query(ctx, sql, query)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - Search - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]entity.Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - Search - rows.Scan: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// SearchCount -. only select from database
+func (bdr *BookDatabaseRepo) SearchCount(ctx context.Context, query string) (int, error) {
+	sql := `SELECT count(*) FROM library_book WHERE deleted_at IS NULL AND ` + searchCondition
+
+	row := bdr.Pool.QueryRow(ctx, sql, query)
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("BookDatabaseRepo - SearchCount - r.Pool.QueryRow: %w", err)
+	}
+
+	return count, nil
+}
+
 // Get -. only select from database
 func (bdr *BookDatabaseRepo) GetById(ctx context.Context, id string) (entity.Book, error) {
-	sql := `
-		SELECT id, title, author, publisher, year, created_at, updated_at, isbn, storage_file_path, koreader_partial_md5, storage_cover_path
-		FROM library_book
-		WHERE id = $1
-	`
+	sql := `SELECT ` + bookColumns + ` FROM library_book WHERE id = $1 AND deleted_at IS NULL`
 	args := []interface{}{id}
 
 	row := bdr.Pool.QueryRow(ctx, sql, args...)
-	var book entity.Book
-	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.Year, &book.CreatedAt, &book.UpdatedAt, &book.ISBN, &book.FilePath, &book.DocumentID, &book.CoverPath)
+	book, err := scanBook(row)
 	if err != nil {
 		return entity.Book{}, fmt.Errorf("BookDatabaseRepo - Get - r.Pool.QueryRow: %w", err)
 	}
@@ -141,18 +207,14 @@ func (bdr *BookDatabaseRepo) GetById(ctx context.Context, id string) (entity.Boo
 	return book, nil
 }
 
-// GetByFileHash -. only select from database
+// GetByFileHash -. only select from database, books in trash, previous hashes and formats are included
 func (bdr *BookDatabaseRepo) GetByFileHash(ctx context.Context, fileHash string) (entity.Book, error) {
-	sql := `
-		SELECT id, title, author, publisher, year, created_at, updated_at, isbn, storage_file_path, koreader_partial_md5, storage_cover_path
-		FROM library_book
-		WHERE koreader_partial_md5 = $1
-	`
+	sql := `SELECT ` + bookColumns + ` FROM library_book
+		WHERE id IN (SELECT library_book_id FROM library_book_file WHERE koreader_partial_md5 = $1)`
 	args := []interface{}{fileHash}
 
 	row := bdr.Pool.QueryRow(ctx, sql, args...)
-	var book entity.Book
-	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Publisher, &book.Year, &book.CreatedAt, &book.UpdatedAt, &book.ISBN, &book.FilePath, &book.DocumentID, &book.CoverPath)
+	book, err := scanBook(row)
 	if err != nil {
 		return entity.Book{}, fmt.Errorf("BookDatabaseRepo - GetByFileHash - r.Pool.QueryRow: %w", err)
 	}
@@ -162,7 +224,7 @@ func (bdr *BookDatabaseRepo) GetByFileHash(ctx context.Context, fileHash string)
 
 // Count -. only select from database
 func (bdr *BookDatabaseRepo) Count(ctx context.Context) (int, error) {
-	sql := `SELECT count(*) FROM library_book`
+	sql := `SELECT count(*) FROM library_book WHERE deleted_at IS NULL`
 
 	row := bdr.Pool.QueryRow(ctx, sql)
 	var count int
@@ -173,3 +235,407 @@ func (bdr *BookDatabaseRepo) Count(ctx context.Context) (int, error) {
 
 	return count, nil
 }
+
+// ListFiltered -. only select from database
+func (bdr *BookDatabaseRepo) ListFiltered(ctx context.Context,
+	filter BookFilter,
+	sortBy, sortOrder string,
+	page, perPage int,
+) ([]entity.Book, error) {
+	switch sortOrder {
+	case "asc", "desc":
+	default:
+		sortOrder = "desc"
+	}
+
+	where, args := filter.where()
+
+	orderBy := sortBy
+	switch sortBy {
+	case "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "series":
+	case "last_activity":
+		orderBy = "coalesce(progress_at, created_at)"
+	case "series_index":
+		// volumes without number go last, order is applied to titles as well
+		orderBy = fmt.Sprintf("series_index %s NULLS LAST, title", sortOrder)
+	case "relevance":
+		query := strings.TrimSpace(filter.Query)
+		if query == "" {
+			sortBy, orderBy = "created_at", "created_at"
+			break
+		}
+		// best matches first, order is applied to creation time of equal ones
+		args = append(args, query)
+		orderBy = fmt.Sprintf(`ts_rank(to_tsvector('simple', search_text), plainto_tsquery('simple', $%[1]d)) DESC,
+			word_similarity($%[1]d, search_text) DESC,
+			created_at`, len(args))
+	default:
+		sortBy, orderBy = "created_at", "created_at"
+	}
+
+	page, perPage = normalizePagination(page, perPage)
+
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE %s
+		ORDER BY %s %s
+		LIMIT %d OFFSET %d
+	`, bookColumns, filter.from(sortBy), where, orderBy, sortOrder, perPage, (page-1)*perPage)
+
+	rows, err := bdr.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListFiltered - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]entity.Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - ListFiltered - rows.Scan: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// CountFiltered -. only select from database
+func (bdr *BookDatabaseRepo) CountFiltered(ctx context.Context, filter BookFilter) (int, error) {
+	where, args := filter.where()
+	sql := `SELECT count(*) FROM ` + filter.from("") + ` WHERE ` + where
+
+	row := bdr.Pool.QueryRow(ctx, sql, args...)
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("BookDatabaseRepo - CountFiltered - r.Pool.QueryRow: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListGroups -. distinct values of the field with number of books
+func (bdr *BookDatabaseRepo) ListGroups(ctx context.Context, field string) ([]BookGroup, error) {
+	if !isGroupField(field) {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListGroups - unknown field: %s", field)
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT %[1]s, count(*)
+		FROM library_book
+		WHERE %[1]s IS NOT NULL AND %[1]s <> '' AND deleted_at IS NULL
+		GROUP BY %[1]s
+		ORDER BY %[1]s
+	`, field)
+
+	rows, err := bdr.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListGroups - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]BookGroup, 0)
+	for rows.Next() {
+		var group BookGroup
+		err = rows.Scan(&group.Value, &group.Count)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - ListGroups - rows.Scan: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// ReplaceFile -. points book to the new file and keeps previous hash as alias
+func (bdr *BookDatabaseRepo) ReplaceFile(ctx context.Context, book entity.Book, previousHash string) error {
+	sql := `
+		WITH dropped AS (
+			DELETE FROM library_book_alias WHERE koreader_partial_md5 = $3
+		), alias AS (
+			INSERT INTO library_book_alias (koreader_partial_md5, library_book_id)
+			VALUES ($2, $1)
+			ON CONFLICT (koreader_partial_md5) DO NOTHING
+		)
+		UPDATE library_book
+		SET storage_file_path = $4,
+			koreader_partial_md5 = $3,
+			updated_at = $5,
+			pages = $6
+		WHERE id = $1
+	`
+	args := []interface{}{book.ID, previousHash, book.DocumentID, book.FilePath, book.UpdatedAt, book.Pages}
+
+	rows, err := bdr.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - ReplaceFile - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - ReplaceFile - no rows affected")
+	}
+	return nil
+}
+
+// UpdateCover -. only update in database
+func (bdr *BookDatabaseRepo) UpdateCover(ctx context.Context, id, coverPath string) error {
+	sql := `UPDATE library_book SET storage_cover_path = $1, updated_at = now() WHERE id = $2`
+
+	rows, err := bdr.Pool.Exec(ctx, sql, coverPath, id)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - UpdateCover - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - UpdateCover - no rows affected")
+	}
+	return nil
+}
+
+// Delete -. moves book to trash
+func (bdr *BookDatabaseRepo) Delete(ctx context.Context, id string) error {
+	sql := `UPDATE library_book SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+
+	rows, err := bdr.Pool.Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Delete - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - Delete - no rows affected")
+	}
+	return nil
+}
+
+// Restore -. returns book from trash
+func (bdr *BookDatabaseRepo) Restore(ctx context.Context, id string) error {
+	sql := `UPDATE library_book SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	rows, err := bdr.Pool.Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Restore - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - Restore - no rows affected")
+	}
+	return nil
+}
+
+// ListDeleted -. books in trash, recently deleted first
+func (bdr *BookDatabaseRepo) ListDeleted(ctx context.Context) ([]entity.Book, error) {
+	sql := `SELECT ` + bookColumns + ` FROM library_book WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+
+	rows, err := bdr.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListDeleted - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]entity.Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - ListDeleted - rows.Scan: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// ListAll -. all books not in trash, oldest first
+func (bdr *BookDatabaseRepo) ListAll(ctx context.Context) ([]entity.Book, error) {
+	sql := `SELECT ` + bookColumns + ` FROM library_book WHERE deleted_at IS NULL ORDER BY created_at`
+
+	rows, err := bdr.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListAll - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]entity.Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - ListAll - rows.Scan: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// Merge -. moves files, formats, aliases and collections of duplicates
+// to the book, removes duplicates and updates the book in a transaction
+func (bdr *BookDatabaseRepo) Merge(ctx context.Context, book entity.Book, duplicates []entity.Book) error {
+	return pgx.BeginFunc(ctx, bdr.Pool, func(tx pgx.Tx) error {
+		for _, duplicate := range duplicates {
+			if err := mergeDuplicate(ctx, tx, book.ID, duplicate); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.Exec(ctx, updateBookSQL, updateBookArgs(book)...)
+		if err != nil {
+			return fmt.Errorf("BookDatabaseRepo - Merge - update book: %w", err)
+		}
+		if rows.RowsAffected() == 0 {
+			return fmt.Errorf("BookDatabaseRepo - Merge - no rows affected")
+		}
+		_, err = tx.Exec(ctx, `UPDATE library_book SET storage_cover_path = $1 WHERE id = $2`, book.CoverPath, book.ID)
+		if err != nil {
+			return fmt.Errorf("BookDatabaseRepo - Merge - update cover: %w", err)
+		}
+		return nil
+	})
+}
+
+func mergeDuplicate(ctx context.Context, tx pgx.Tx, id string, duplicate entity.Book) error {
+	sql := `
+		INSERT INTO library_book_format (koreader_partial_md5, library_book_id, format, storage_file_path, pages)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (koreader_partial_md5) DO NOTHING
+	`
+	_, err := tx.Exec(ctx, sql, duplicate.DocumentID, id, duplicate.FileFormat(), duplicate.FilePath, duplicate.Pages)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Merge - insert format: %w", err)
+	}
+
+	sql = `UPDATE library_book_format SET library_book_id = $1 WHERE library_book_id = $2`
+	_, err = tx.Exec(ctx, sql, id, duplicate.ID)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Merge - move formats: %w", err)
+	}
+
+	// aliases of duplicate belong to its file, which is a format now
+	sql = `UPDATE library_book_alias SET library_book_id = $1, detached = TRUE WHERE library_book_id = $2`
+	_, err = tx.Exec(ctx, sql, id, duplicate.ID)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Merge - move aliases: %w", err)
+	}
+
+	sql = `
+		INSERT INTO library_collection_book (library_collection_id, library_book_id)
+		SELECT library_collection_id, $1 FROM library_collection_book WHERE library_book_id = $2
+		ON CONFLICT DO NOTHING
+	`
+	_, err = tx.Exec(ctx, sql, id, duplicate.ID)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Merge - copy collections: %w", err)
+	}
+
+	rows, err := tx.Exec(ctx, `DELETE FROM library_book WHERE id = $1`, duplicate.ID)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Merge - delete duplicate: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - Merge - no rows affected")
+	}
+	return nil
+}
+
+// ListFormats -. additional files of the books, oldest first
+func (bdr *BookDatabaseRepo) ListFormats(ctx context.Context, ids []string) ([]entity.BookFormat, error) {
+	sql := `
+		SELECT library_book_id, koreader_partial_md5, storage_file_path, format, coalesce(pages, 0), created_at
+		FROM library_book_format
+		WHERE library_book_id = ANY($1::uuid[])
+		ORDER BY created_at
+	`
+
+	rows, err := bdr.Pool.Query(ctx, sql, ids)
+	if err != nil {
+		return nil, fmt.Errorf("BookDatabaseRepo - ListFormats - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	formats := make([]entity.BookFormat, 0)
+	for rows.Next() {
+		var format entity.BookFormat
+		err = rows.Scan(&format.BookID, &format.DocumentID, &format.FilePath, &format.Format, &format.Pages, &format.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("BookDatabaseRepo - ListFormats - rows.Scan: %w", err)
+		}
+		formats = append(formats, format)
+	}
+
+	return formats, nil
+}
+
+// StoreFormat -. only insert in database
+func (bdr *BookDatabaseRepo) StoreFormat(ctx context.Context, format entity.BookFormat) error {
+	sql := `
+		INSERT INTO library_book_format (koreader_partial_md5, library_book_id, format, storage_file_path, pages, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	args := []interface{}{format.DocumentID, format.BookID, format.Format, format.FilePath, format.Pages, format.CreatedAt}
+
+	_, err := bdr.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return fmt.Errorf("BookDatabaseRepo - StoreFormat - r.Pool.Exec: %w", entity.ErrBookAlreadyExists)
+		}
+		return fmt.Errorf("BookDatabaseRepo - StoreFormat - r.Pool.Exec: %w", err)
+	}
+	return nil
+}
+
+// DeleteFormat -. removes file of the book and keeps its hash as detached alias,
+// so stats stay with the book, but progress is not synced with the main file
+func (bdr *BookDatabaseRepo) DeleteFormat(ctx context.Context, id, documentID string) error {
+	sql := `
+		WITH format AS (
+			DELETE FROM library_book_format
+			WHERE library_book_id = $1 AND koreader_partial_md5 = $2
+			RETURNING library_book_id, koreader_partial_md5
+		)
+		INSERT INTO library_book_alias (koreader_partial_md5, library_book_id, detached)
+		SELECT koreader_partial_md5, library_book_id, TRUE FROM format
+		ON CONFLICT (koreader_partial_md5) DO NOTHING
+	`
+
+	rows, err := bdr.Pool.Exec(ctx, sql, id, documentID)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - DeleteFormat - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("BookDatabaseRepo - DeleteFormat - no rows affected")
+	}
+	return nil
+}
+
+// Purge -. removes book from trash forever
+func (bdr *BookDatabaseRepo) Purge(ctx context.Context, id string) error {
+	sql := `DELETE FROM library_book WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	_, err := bdr.Pool.Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("BookDatabaseRepo - Purge - r.Pool.Exec: %w", err)
+	}
+	return nil
+}
+
+func scanBook(row pgx.Row) (entity.Book, error) {
+	var book entity.Book
+	var deletedAt *time.Time
+	err := row.Scan(
+		&book.ID, &book.Title, &book.Author, &book.Publisher, &book.Year,
+		&book.CreatedAt, &book.UpdatedAt, &book.ISBN,
+		&book.FilePath, &book.DocumentID, &book.CoverPath,
+		&book.Series, &book.Language, &book.Description, &deletedAt,
+		&book.SeriesIndex, &book.Subjects, &book.Pages,
+	)
+	if deletedAt != nil {
+		book.DeletedAt = *deletedAt
+	}
+	return book, err
+}
+
+func normalizePagination(page, perPage int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 || perPage > 100 {
+		perPage = 25
+	}
+	return page, perPage
+}
@@ -2,6 +2,7 @@ package library_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -14,17 +15,22 @@ import (
 func TestBookDatabaseRepoCreate(t *testing.T) {
 	// book
 	book := entity.Book{
-		ID:         "1",
-		Title:      "title",
-		Author:     "author",
-		Publisher:  "publisher",
-		Year:       2021,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		ISBN:       "isbn",
-		FilePath:   "file_path",
-		DocumentID: "document_id",
-		CoverPath:  "cover_path",
+		ID:          "1",
+		Title:       "title",
+		Author:      "author",
+		Publisher:   "publisher",
+		Year:        2021,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
 	}
 
 	// создать mock
@@ -32,7 +38,7 @@ func TestBookDatabaseRepoCreate(t *testing.T) {
 	defer mock.Close()
 
 	mock.ExpectExec("INSERT INTO library_book").
-		WithArgs(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath).
+		WithArgs(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, book.SeriesIndex, book.Subjects, book.Pages).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 	// вызвать Create
@@ -45,25 +51,30 @@ func TestBookDatabaseRepoCreate(t *testing.T) {
 func TestBookDatabaseRepoGetById(t *testing.T) {
 	// book
 	book := entity.Book{
-		ID:         "1",
-		Title:      "title",
-		Author:     "author",
-		Publisher:  "publisher",
-		Year:       2021,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		ISBN:       "isbn",
-		FilePath:   "file_path",
-		DocumentID: "document_id",
-		CoverPath:  "cover_path",
+		ID:          "1",
+		Title:       "title",
+		Author:      "author",
+		Publisher:   "publisher",
+		Year:        2021,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
 	}
 
 	// создать mock
 	mock, bdr := setupTestBookDatabaseRepo()
 	defer mock.Close()
 
-	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path"}).
-		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath)
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, nil, book.SeriesIndex, book.Subjects, book.Pages)
 
 	mock.ExpectQuery("SELECT (.+) FROM library_book").
 		WithArgs(book.ID).
@@ -83,25 +94,30 @@ func TestBookDatabaseRepoGetById(t *testing.T) {
 func TestBookDatabaseRepoGetByFileHash(t *testing.T) {
 	// book
 	book := entity.Book{
-		ID:         "1",
-		Title:      "title",
-		Author:     "author",
-		Publisher:  "publisher",
-		Year:       2021,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		ISBN:       "isbn",
-		FilePath:   "file_path",
-		DocumentID: "document_id",
-		CoverPath:  "cover_path",
+		ID:          "1",
+		Title:       "title",
+		Author:      "author",
+		Publisher:   "publisher",
+		Year:        2021,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
 	}
 
 	// создать mock
 	mock, bdr := setupTestBookDatabaseRepo()
 	defer mock.Close()
 
-	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path"}).
-		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath)
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, nil, book.SeriesIndex, book.Subjects, book.Pages)
 
 	mock.ExpectQuery("SELECT (.+) FROM library_book").
 		WithArgs(book.DocumentID).
@@ -121,25 +137,30 @@ func TestBookDatabaseRepoGetByFileHash(t *testing.T) {
 func TestBookDatabaseRepoList(t *testing.T) {
 	// book
 	book := entity.Book{
-		ID:         "1",
-		Title:      "title",
-		Author:     "author",
-		Publisher:  "publisher",
-		Year:       2021,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		ISBN:       "isbn",
-		FilePath:   "file_path",
-		DocumentID: "document_id",
-		CoverPath:  "cover_path",
+		ID:          "1",
+		Title:       "title",
+		Author:      "author",
+		Publisher:   "publisher",
+		Year:        2021,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
 	}
 
 	// создать mock
 	mock, bdr := setupTestBookDatabaseRepo()
 	defer mock.Close()
 
-	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path"}).
-		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath)
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, nil, book.SeriesIndex, book.Subjects, book.Pages)
 
 	mock.ExpectQuery("SELECT (.+) FROM library_book").
 		WillReturnRows(rows)
@@ -159,6 +180,266 @@ func TestBookDatabaseRepoList(t *testing.T) {
 	}
 }
 
+func TestBookDatabaseRepoSearch(t *testing.T) {
+	// book
+	book := entity.Book{
+		ID:          "1",
+		Title:       "Crime and Punishment",
+		Author:      "Fyodor Dostoevsky",
+		Publisher:   "publisher",
+		Year:        1866,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
+	}
+
+	// создать mock
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, nil, book.SeriesIndex, book.Subjects, book.Pages)
+
+	mock.ExpectQuery("SELECT (.+) FROM library_book WHERE (.+) search_text").
+		WithArgs("dostoevsky").
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT count(.+) FROM library_book WHERE (.+) search_text").
+		WithArgs("dostoevsky").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+
+	// вызвать Search
+	results, err := bdr.Search(context.Background(), "dostoevsky", 1, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %v", len(results))
+	}
+	if results[0].Title != book.Title {
+		t.Errorf("expected Title %v, got %v", book.Title, results[0].Title)
+	}
+
+	count, err := bdr.SearchCount(context.Background(), "dostoevsky")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected count 1, got %v", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListFiltered(t *testing.T) {
+	// book
+	book := entity.Book{
+		ID:          "1",
+		Title:       "title",
+		Author:      "author",
+		Publisher:   "publisher",
+		Year:        2021,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ISBN:        "isbn",
+		FilePath:    "file_path",
+		DocumentID:  "document_id",
+		CoverPath:   "cover_path",
+		Series:      "series",
+		Language:    "en",
+		Description: "description",
+		SeriesIndex: 2,
+		Subjects:    []string{"fiction"},
+	}
+
+	// создать mock
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow(book.ID, book.Title, book.Author, book.Publisher, book.Year, book.CreatedAt, book.UpdatedAt, book.ISBN, book.FilePath, book.DocumentID, book.CoverPath, book.Series, book.Language, book.Description, nil, book.SeriesIndex, book.Subjects, book.Pages)
+
+	mock.ExpectQuery("SELECT (.+) FROM library_book WHERE deleted_at IS NULL AND author = \\$1 AND language = \\$2").
+		WithArgs(book.Author, book.Language).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT count(.+) FROM library_book WHERE deleted_at IS NULL AND author = \\$1 AND language = \\$2").
+		WithArgs(book.Author, book.Language).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+
+	filter := library.BookFilter{Author: book.Author, Language: book.Language}
+	results, err := bdr.ListFiltered(context.Background(), filter, "title", "asc", 1, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %v", len(results))
+	}
+	if results[0].Series != book.Series {
+		t.Errorf("expected Series %v, got %v", book.Series, results[0].Series)
+	}
+
+	count, err := bdr.CountFiltered(context.Background(), filter)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected count 1, got %v", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListFilteredByStatus(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow("1", "title", "author", "publisher", 2021, time.Now(), time.Now(), "isbn", "file_path", "document_id", "cover_path", "", "", "", nil, 0.0, []string{}, 0)
+
+	mock.ExpectQuery("SELECT (.+) FROM library_book\\s+LEFT JOIN LATERAL (.+) WHERE deleted_at IS NULL AND progress_percentage > 0 AND progress_percentage < 1\\s+ORDER BY coalesce\\(progress_at, created_at\\) desc").
+		WillReturnRows(rows)
+
+	filter := library.BookFilter{Status: library.StatusReading}
+	results, err := bdr.ListFiltered(context.Background(), filter, "last_activity", "desc", 1, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %v", len(results))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListFilteredBySeriesIndex(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow("1", "title", "author", "publisher", 2021, time.Now(), time.Now(), "isbn", "file_path", "document_id", "cover_path", "Dune", "", "", nil, 2.0, []string{}, 0)
+
+	mock.ExpectQuery("SELECT (.+) FROM library_book WHERE deleted_at IS NULL AND series = \\$1\\s+ORDER BY series_index asc NULLS LAST, title asc").
+		WithArgs("Dune").
+		WillReturnRows(rows)
+
+	filter := library.BookFilter{Series: "Dune"}
+	results, err := bdr.ListFiltered(context.Background(), filter, "series_index", "asc", 1, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %v", len(results))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoSearchEscapesWildcards(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	// wildcards of the query are escaped in database, query is passed as is
+	mock.ExpectQuery(`SELECT count(.+) search_text ILIKE '%' \|\| replace\(replace\(replace\(\$1, '\\', '\\\\'\), '%', '\\%'\), '_', '\\_'\) \|\| '%' ESCAPE '\\'`).
+		WithArgs("100%_").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+
+	count, err := bdr.SearchCount(context.Background(), "100%_")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected count 0, got %v", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListFilteredBySearch(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "title", "author", "publisher", "year", "created_at", "updated_at", "isbn", "file_path", "file_hash", "cover_path", "series", "language", "summary", "deleted_at", "series_index", "subjects", "pages"}).
+		AddRow("1", "Dune", "Frank Herbert", "publisher", 1965, time.Now(), time.Now(), "isbn", "books/1.epub", "document_id", "cover_path", "", "en", "", nil, 0.0, []string{}, 0)
+
+	mock.ExpectQuery("SELECT (.+) FROM library_book WHERE deleted_at IS NULL AND language = \\$1 AND (.+)plainto_tsquery\\('simple', \\$2\\)(.+) AND \\(CASE(.+)END = \\$3 OR EXISTS \\(SELECT 1 FROM library_book_format (.+)library_book_format.format = \\$3\\)\\)\\s+ORDER BY ts_rank(.+)\\$4(.+)created_at desc").
+		WithArgs("en", "dune", "epub", "dune").
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT count(.+) FROM library_book WHERE deleted_at IS NULL AND language = \\$1 AND (.+) AND \\(CASE(.+)END = \\$3 OR EXISTS \\(SELECT 1 FROM library_book_format (.+)library_book_format.format = \\$3\\)\\)").
+		WithArgs("en", "dune", "epub").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+
+	filter := library.BookFilter{Query: " dune ", Language: "en", Format: "epub"}
+	results, err := bdr.ListFiltered(context.Background(), filter, "relevance", "desc", 1, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %v", len(results))
+	}
+
+	count, err := bdr.CountFiltered(context.Background(), filter)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected count 1, got %v", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListGroups(t *testing.T) {
+	// создать mock
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"series", "count"}).
+		AddRow("Discworld", 41).
+		AddRow("Dune", 6)
+
+	mock.ExpectQuery("SELECT series, count(.+) FROM library_book (.+) GROUP BY series").
+		WillReturnRows(rows)
+
+	groups, err := bdr.ListGroups(context.Background(), library.GroupBySeries)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %v", len(groups))
+	}
+	if groups[0].Value != "Discworld" || groups[0].Count != 41 {
+		t.Errorf("unexpected group %+v", groups[0])
+	}
+
+	_, err = bdr.ListGroups(context.Background(), "storage_file_path")
+	if err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func setupTestBookDatabaseRepo() (pgxmock.PgxPoolIface, *library.BookDatabaseRepo) {
 	// создать mock
 	mock, err := pgxmock.NewPool()
@@ -172,3 +453,178 @@ func setupTestBookDatabaseRepo() (pgxmock.PgxPoolIface, *library.BookDatabaseRep
 
 	return mock, bdr
 }
+
+func TestBookDatabaseRepoDeleteRestore(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE library_book SET deleted_at = now\\(\\) WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs("1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE library_book SET deleted_at = NULL WHERE id = \\$1 AND deleted_at IS NOT NULL").
+		WithArgs("1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE library_book SET deleted_at = NULL").
+		WithArgs("1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err := bdr.Delete(context.Background(), "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := bdr.Restore(context.Background(), "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := bdr.Restore(context.Background(), "1"); err == nil {
+		t.Errorf("expected error on restoring book not in trash")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoReplaceFile(t *testing.T) {
+	book := entity.Book{
+		ID:         "1",
+		UpdatedAt:  time.Now(),
+		FilePath:   "new_file_path",
+		DocumentID: "new_document_id",
+	}
+
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO library_book_alias (.+) UPDATE library_book").
+		WithArgs(book.ID, "old_document_id", book.DocumentID, book.FilePath, book.UpdatedAt, book.Pages).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err := bdr.ReplaceFile(context.Background(), book, "old_document_id")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoMerge(t *testing.T) {
+	book := entity.Book{ID: "1", Title: "Dune", CoverPath: "covers/2.jpg", UpdatedAt: time.Now()}
+	duplicate := entity.Book{ID: "2", DocumentID: "pdf_hash", FilePath: "2026/01/01/2.pdf", Pages: 300}
+
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO library_book_format").
+		WithArgs(duplicate.DocumentID, "1", "pdf", duplicate.FilePath, duplicate.Pages).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("UPDATE library_book_format").
+		WithArgs("1", duplicate.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec("UPDATE library_book_alias").
+		WithArgs("1", duplicate.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("INSERT INTO library_collection_book").
+		WithArgs("1", duplicate.ID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("DELETE FROM library_book WHERE id = \\$1").
+		WithArgs(duplicate.ID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("UPDATE library_book SET title").
+		WithArgs(book.Title, book.Author, book.Publisher, book.Year,
+			book.UpdatedAt, book.ISBN, book.Series, book.Language,
+			book.Description, book.SeriesIndex, book.Subjects, book.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE library_book SET storage_cover_path").
+		WithArgs(book.CoverPath, book.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	// pgx.BeginFunc always rolls back, it is a no-op after commit
+	mock.ExpectRollback()
+
+	err := bdr.Merge(context.Background(), book, []entity.Book{duplicate})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoMergeFailure(t *testing.T) {
+	book := entity.Book{ID: "1", Title: "Dune"}
+	first := entity.Book{ID: "2", DocumentID: "pdf_hash", FilePath: "2026/01/01/2.pdf"}
+	second := entity.Book{ID: "3", DocumentID: "fb2_hash", FilePath: "2026/01/01/3.fb2"}
+
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO library_book_format").
+		WithArgs(first.DocumentID, "1", "pdf", first.FilePath, first.Pages).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("UPDATE library_book_format").
+		WithArgs("1", first.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec("UPDATE library_book_alias").
+		WithArgs("1", first.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec("INSERT INTO library_collection_book").
+		WithArgs("1", first.ID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectExec("DELETE FROM library_book WHERE id = \\$1").
+		WithArgs(first.ID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("INSERT INTO library_book_format").
+		WithArgs(second.DocumentID, "1", "fb2", second.FilePath, second.Pages).
+		WillReturnError(errors.New("some error"))
+	// first duplicate is rolled back with the failed one, book is not updated
+	mock.ExpectRollback()
+
+	err := bdr.Merge(context.Background(), book, []entity.Book{first, second})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoFormats(t *testing.T) {
+	format := entity.BookFormat{BookID: "1", DocumentID: "pdf_hash", FilePath: "2026/01/01/2.pdf", Format: "pdf", Pages: 300, CreatedAt: time.Now()}
+
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO library_book_format").
+		WithArgs(format.DocumentID, format.BookID, format.Format, format.FilePath, format.Pages, format.CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("SELECT (.+) FROM library_book_format WHERE library_book_id = ANY\\(\\$1::uuid\\[\\]\\)").
+		WithArgs([]string{"1"}).
+		WillReturnRows(pgxmock.NewRows([]string{"library_book_id", "koreader_partial_md5", "storage_file_path", "format", "pages", "created_at"}).
+			AddRow(format.BookID, format.DocumentID, format.FilePath, format.Format, format.Pages, format.CreatedAt))
+	mock.ExpectExec("DELETE FROM library_book_format (.+) INSERT INTO library_book_alias").
+		WithArgs("1", format.DocumentID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	if err := bdr.StoreFormat(context.Background(), format); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	formats, err := bdr.ListFormats(context.Background(), []string{"1"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(formats) != 1 || formats[0] != format {
+		t.Errorf("unexpected formats %+v", formats)
+	}
+	if err := bdr.DeleteFormat(context.Background(), "1", format.DocumentID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
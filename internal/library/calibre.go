@@ -0,0 +1,103 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/calibre"
+)
+
+// calibreFormats is an order in which formats of a Calibre book are tried.
+var calibreFormats = []string{"EPUB", "AZW3", "MOBI", "FB2", "PDF"}
+
+// ImportCalibre imports one file per book of Calibre library
+// and overwrites extracted metadata and cover with curated ones from Calibre.
+func (i *Importer) ImportCalibre(ctx context.Context, dir string) (ImportReport, error) {
+	var report ImportReport
+
+	lib, err := calibre.Open(dir)
+	if err != nil {
+		return report, fmt.Errorf("Importer - ImportCalibre - calibre.Open: %w", err)
+	}
+	defer lib.Close()
+
+	books, err := lib.Books(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Importer - ImportCalibre - lib.Books: %w", err)
+	}
+
+	for _, cb := range books {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		report.add(i.importCalibreBook(ctx, lib, cb))
+	}
+	return report, nil
+}
+
+func (i *Importer) importCalibreBook(ctx context.Context, lib *calibre.Library, cb calibre.Book) ImportResult {
+	result := ImportResult{Name: cb.Path, Status: ImportSkipped, Err: entity.ErrUnsupportedFormat}
+	for _, format := range sortCalibreFormats(cb.Formats) {
+		result = i.importFile(ctx, lib.FilePath(cb, format), cb.Path+"/"+format.Filename())
+		if result.Status != ImportSkipped {
+			break
+		}
+	}
+	if result.Status != ImportStored {
+		return result
+	}
+
+	book, err := i.shelf.UpdateBookMetadata(ctx, result.Book.ID, calibreToBook(cb))
+	if err != nil {
+		i.logger.Error("Importer - ImportCalibre - UpdateBookMetadata %s: %s", cb.Path, err)
+		return result
+	}
+	result.Book = book
+
+	if cb.HasCover {
+		cover, err := os.ReadFile(lib.CoverPath(cb))
+		if err == nil {
+			_, err = i.shelf.UpdateCover(ctx, book.ID, cover)
+		}
+		if err != nil {
+			i.logger.Error("Importer - ImportCalibre - UpdateCover %s: %s", cb.Path, err)
+		}
+	}
+	return result
+}
+
+func calibreToBook(cb calibre.Book) entity.Book {
+	return entity.Book{
+		Title:       cb.Title,
+		Author:      strings.Join(cb.Authors, ", "),
+		Publisher:   cb.Publisher,
+		Year:        cb.Year,
+		ISBN:        cb.ISBN,
+		Series:      cb.Series,
+		SeriesIndex: cb.SeriesIndex,
+		Language:    cb.Language,
+		Description: cb.Comments,
+		Subjects:    cb.Tags,
+	}
+}
+
+// sortCalibreFormats orders formats by preference, unknown ones go last.
+func sortCalibreFormats(formats []calibre.Format) []calibre.Format {
+	rank := func(f calibre.Format) int {
+		for i, known := range calibreFormats {
+			if strings.EqualFold(known, f.Format) {
+				return i
+			}
+		}
+		return len(calibreFormats)
+	}
+	sorted := append([]calibre.Format(nil), formats...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return rank(sorted[a]) < rank(sorted[b])
+	})
+	return sorted
+}
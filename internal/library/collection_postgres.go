@@ -0,0 +1,158 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/postgres"
+)
+
+// collectionColumns is a list of columns scanned by scanCollection,
+// books in trash are not counted.
+const collectionColumns = `c.id, c.name, c.created_at, c.updated_at,
+	(SELECT count(*) FROM library_collection_book cb
+		JOIN library_book b ON b.id = cb.library_book_id
+		WHERE cb.library_collection_id = c.id AND b.deleted_at IS NULL)`
+
+// CollectionDatabaseRepo -.
+type CollectionDatabaseRepo struct {
+	*postgres.Postgres
+}
+
+// NewCollectionDatabaseRepo -.
+func NewCollectionDatabaseRepo(pg *postgres.Postgres) *CollectionDatabaseRepo {
+	return &CollectionDatabaseRepo{pg}
+}
+
+// Store -. only insert in database
+func (cdr *CollectionDatabaseRepo) Store(ctx context.Context, collection entity.Collection) error {
+	sql := `INSERT INTO library_collection (id, name, created_at, updated_at) VALUES ($1, $2, $3, $4)`
+
+	_, err := cdr.Pool.Exec(ctx, sql, collection.ID, collection.Name, collection.CreatedAt, collection.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return fmt.Errorf("CollectionDatabaseRepo - Store - r.Pool.Exec: %w", entity.ErrCollectionAlreadyExists)
+		}
+		return fmt.Errorf("CollectionDatabaseRepo - Store - r.Pool.Exec: %w", err)
+	}
+	return nil
+}
+
+// Update -. renames collection
+func (cdr *CollectionDatabaseRepo) Update(ctx context.Context, collection entity.Collection) error {
+	sql := `UPDATE library_collection SET name = $1, updated_at = $2 WHERE id = $3`
+
+	rows, err := cdr.Pool.Exec(ctx, sql, collection.Name, collection.UpdatedAt, collection.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return fmt.Errorf("CollectionDatabaseRepo - Update - r.Pool.Exec: %w", entity.ErrCollectionAlreadyExists)
+		}
+		return fmt.Errorf("CollectionDatabaseRepo - Update - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("CollectionDatabaseRepo - Update - no rows affected")
+	}
+	return nil
+}
+
+// Delete -. removes collection, books are kept
+func (cdr *CollectionDatabaseRepo) Delete(ctx context.Context, id string) error {
+	sql := `DELETE FROM library_collection WHERE id = $1`
+
+	rows, err := cdr.Pool.Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("CollectionDatabaseRepo - Delete - r.Pool.Exec: %w", err)
+	}
+	if rows.RowsAffected() == 0 {
+		return fmt.Errorf("CollectionDatabaseRepo - Delete - no rows affected")
+	}
+	return nil
+}
+
+// GetById -. only select from database
+func (cdr *CollectionDatabaseRepo) GetById(ctx context.Context, id string) (entity.Collection, error) {
+	sql := `SELECT ` + collectionColumns + ` FROM library_collection c WHERE c.id = $1`
+
+	collection, err := scanCollection(cdr.Pool.QueryRow(ctx, sql, id))
+	if err != nil {
+		return entity.Collection{}, fmt.Errorf("CollectionDatabaseRepo - GetById - r.Pool.QueryRow: %w", err)
+	}
+	return collection, nil
+}
+
+// List -. all collections ordered by name
+func (cdr *CollectionDatabaseRepo) List(ctx context.Context) ([]entity.Collection, error) {
+	sql := `SELECT ` + collectionColumns + ` FROM library_collection c ORDER BY c.name`
+
+	rows, err := cdr.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("CollectionDatabaseRepo - List - r.Pool.Query: %w", err)
+	}
+	return collectCollections(rows, "List")
+}
+
+// ListByBook -. collections of the book ordered by name
+func (cdr *CollectionDatabaseRepo) ListByBook(ctx context.Context, bookID string) ([]entity.Collection, error) {
+	sql := `
+		SELECT ` + collectionColumns + `
+		FROM library_collection c
+		JOIN library_collection_book cb ON cb.library_collection_id = c.id
+		WHERE cb.library_book_id = $1
+		ORDER BY c.name
+	`
+
+	rows, err := cdr.Pool.Query(ctx, sql, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("CollectionDatabaseRepo - ListByBook - r.Pool.Query: %w", err)
+	}
+	return collectCollections(rows, "ListByBook")
+}
+
+// AddBook -. adding the same book twice is not an error
+func (cdr *CollectionDatabaseRepo) AddBook(ctx context.Context, id, bookID string) error {
+	sql := `
+		INSERT INTO library_collection_book (library_collection_id, library_book_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := cdr.Pool.Exec(ctx, sql, id, bookID)
+	if err != nil {
+		return fmt.Errorf("CollectionDatabaseRepo - AddBook - r.Pool.Exec: %w", err)
+	}
+	return nil
+}
+
+// RemoveBook -. only delete from database
+func (cdr *CollectionDatabaseRepo) RemoveBook(ctx context.Context, id, bookID string) error {
+	sql := `DELETE FROM library_collection_book WHERE library_collection_id = $1 AND library_book_id = $2`
+
+	_, err := cdr.Pool.Exec(ctx, sql, id, bookID)
+	if err != nil {
+		return fmt.Errorf("CollectionDatabaseRepo - RemoveBook - r.Pool.Exec: %w", err)
+	}
+	return nil
+}
+
+func collectCollections(rows pgx.Rows, method string) ([]entity.Collection, error) {
+	defer rows.Close()
+
+	collections := make([]entity.Collection, 0)
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("CollectionDatabaseRepo - %s - rows.Scan: %w", method, err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+func scanCollection(row pgx.Row) (entity.Collection, error) {
+	var collection entity.Collection
+	err := row.Scan(&collection.ID, &collection.Name, &collection.CreatedAt, &collection.UpdatedAt, &collection.BookCount)
+	return collection, err
+}
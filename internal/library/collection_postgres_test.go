@@ -0,0 +1,122 @@
+package library_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/postgres"
+)
+
+func setupTestCollectionDatabaseRepo() (pgxmock.PgxPoolIface, *library.CollectionDatabaseRepo) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		panic(err)
+	}
+	return mock, library.NewCollectionDatabaseRepo(postgres.Mock(mock))
+}
+
+func TestCollectionDatabaseRepoStore(t *testing.T) {
+	collection := entity.Collection{
+		ID:        "1",
+		Name:      "To read",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	mock, cdr := setupTestCollectionDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO library_collection").
+		WithArgs(collection.ID, collection.Name, collection.CreatedAt, collection.UpdatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO library_collection").
+		WithArgs(collection.ID, collection.Name, collection.CreatedAt, collection.UpdatedAt).
+		WillReturnError(errors.New(`ERROR: duplicate key value violates unique constraint "library_collection_name_key"`))
+
+	if err := cdr.Store(context.Background(), collection); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := cdr.Store(context.Background(), collection); !errors.Is(err, entity.ErrCollectionAlreadyExists) {
+		t.Errorf("expected ErrCollectionAlreadyExists, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCollectionDatabaseRepoListByBook(t *testing.T) {
+	mock, cdr := setupTestCollectionDatabaseRepo()
+	defer mock.Close()
+
+	rows := pgxmock.NewRows([]string{"id", "name", "created_at", "updated_at", "count"}).
+		AddRow("1", "Kids", time.Now(), time.Now(), 3).
+		AddRow("2", "To read", time.Now(), time.Now(), 10)
+	mock.ExpectQuery("SELECT (.+) FROM library_collection c JOIN library_collection_book cb (.+) WHERE cb.library_book_id = \\$1").
+		WithArgs("book").
+		WillReturnRows(rows)
+
+	collections, err := cdr.ListByBook(context.Background(), "book")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(collections) != 2 {
+		t.Fatalf("expected 2 collections, got %v", len(collections))
+	}
+	if collections[1].Name != "To read" || collections[1].BookCount != 10 {
+		t.Errorf("unexpected collection %+v", collections[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCollectionDatabaseRepoAddRemoveBook(t *testing.T) {
+	mock, cdr := setupTestCollectionDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO library_collection_book (.+) ON CONFLICT DO NOTHING").
+		WithArgs("1", "book").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectExec("DELETE FROM library_collection_book WHERE library_collection_id = \\$1 AND library_book_id = \\$2").
+		WithArgs("1", "book").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	if err := cdr.AddBook(context.Background(), "1", "book"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := cdr.RemoveBook(context.Background(), "1", "book"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBookDatabaseRepoListFilteredByCollection(t *testing.T) {
+	mock, bdr := setupTestBookDatabaseRepo()
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT count(.+) FROM library_book WHERE deleted_at IS NULL AND id IN \\(SELECT library_book_id FROM library_collection_book WHERE library_collection_id = \\$1\\)").
+		WithArgs("1").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := bdr.CountFiltered(context.Background(), library.BookFilter{Collection: "1"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected count 2, got %v", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
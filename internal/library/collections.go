@@ -0,0 +1,114 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moroz/uuidv7-go"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// BookCollections organises books into user defined shelves.
+type BookCollections struct {
+	repo   CollectionRepo
+	logger logger.Interface
+}
+
+func NewBookCollections(repo CollectionRepo, l logger.Interface) *BookCollections {
+	return &BookCollections{
+		repo:   repo,
+		logger: l,
+	}
+}
+
+func (uc *BookCollections) ListCollections(ctx context.Context) ([]entity.Collection, error) {
+	collections, err := uc.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("BookCollections - ListCollections - s.repo.List: %w", err)
+	}
+	return collections, nil
+}
+
+func (uc *BookCollections) ViewCollection(ctx context.Context, id string) (entity.Collection, error) {
+	collection, err := uc.repo.GetById(ctx, id)
+	if err != nil {
+		return entity.Collection{}, fmt.Errorf("BookCollections - ViewCollection - s.repo.GetById: %w", err)
+	}
+	return collection, nil
+}
+
+func (uc *BookCollections) CreateCollection(ctx context.Context, name string) (entity.Collection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return entity.Collection{}, fmt.Errorf("BookCollections - CreateCollection - %w", entity.ErrInvalidCollectionName)
+	}
+
+	now := time.Now()
+	collection := entity.Collection{
+		ID:        uuidv7.Generate().String(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := uc.repo.Store(ctx, collection)
+	if err != nil {
+		return entity.Collection{}, fmt.Errorf("BookCollections - CreateCollection - s.repo.Store: %w", err)
+	}
+	return collection, nil
+}
+
+func (uc *BookCollections) RenameCollection(ctx context.Context, id, name string) (entity.Collection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return entity.Collection{}, fmt.Errorf("BookCollections - RenameCollection - %w", entity.ErrInvalidCollectionName)
+	}
+
+	collection, err := uc.repo.GetById(ctx, id)
+	if err != nil {
+		return entity.Collection{}, fmt.Errorf("BookCollections - RenameCollection - s.repo.GetById: %w", err)
+	}
+	collection.Name = name
+	collection.UpdatedAt = time.Now()
+	err = uc.repo.Update(ctx, collection)
+	if err != nil {
+		return entity.Collection{}, fmt.Errorf("BookCollections - RenameCollection - s.repo.Update: %w", err)
+	}
+	return collection, nil
+}
+
+// DeleteCollection removes collection, its books stay in library.
+func (uc *BookCollections) DeleteCollection(ctx context.Context, id string) error {
+	err := uc.repo.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("BookCollections - DeleteCollection - s.repo.Delete: %w", err)
+	}
+	return nil
+}
+
+func (uc *BookCollections) AddBook(ctx context.Context, id, bookID string) error {
+	err := uc.repo.AddBook(ctx, id, bookID)
+	if err != nil {
+		return fmt.Errorf("BookCollections - AddBook - s.repo.AddBook: %w", err)
+	}
+	return nil
+}
+
+func (uc *BookCollections) RemoveBook(ctx context.Context, id, bookID string) error {
+	err := uc.repo.RemoveBook(ctx, id, bookID)
+	if err != nil {
+		return fmt.Errorf("BookCollections - RemoveBook - s.repo.RemoveBook: %w", err)
+	}
+	return nil
+}
+
+func (uc *BookCollections) ListBookCollections(ctx context.Context, bookID string) ([]entity.Collection, error) {
+	collections, err := uc.repo.ListByBook(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("BookCollections - ListBookCollections - s.repo.ListByBook: %w", err)
+	}
+	return collections, nil
+}
@@ -0,0 +1,231 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/converter"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+var (
+	ErrNoConverter    = errors.New("no converter for the format")
+	ErrConversionFull = errors.New("conversion queue is full")
+)
+
+// ConversionJob converts the book to the format.
+type ConversionJob struct {
+	BookID string
+	Format string
+}
+
+// ConversionQueue converts books with external converters in background,
+// converted file is stored as additional format of the book
+// with its own progress, as positions differ between formats.
+// Queue is bounded, jobs above its size are rejected.
+type ConversionQueue struct {
+	shelf      Shelf
+	converters []converter.Converter
+	timeout    time.Duration
+	logger     logger.Interface
+
+	jobs    chan ConversionJob
+	mu      sync.Mutex
+	pending map[ConversionJob]struct{}
+}
+
+func NewConversionQueue(shelf Shelf, converters []converter.Converter, size int, timeout time.Duration, l logger.Interface) *ConversionQueue {
+	return &ConversionQueue{
+		shelf:      shelf,
+		converters: converters,
+		timeout:    timeout,
+		logger:     l,
+		jobs:       make(chan ConversionJob, size),
+		pending:    make(map[ConversionJob]struct{}),
+	}
+}
+
+// Targets returns formats, which the book can be converted to and has not yet.
+func (q *ConversionQueue) Targets(book entity.Book) []string {
+	var targets []string
+	for _, c := range q.converters {
+		if _, err := q.source(book, c); err == nil {
+			targets = append(targets, c.Format())
+		}
+	}
+	return targets
+}
+
+// Pending returns formats, which the book waits to be converted to.
+func (q *ConversionQueue) Pending(bookID string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var formats []string
+	for job := range q.pending {
+		if job.BookID == bookID {
+			formats = append(formats, job.Format)
+		}
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// Enqueue adds conversion of the book, the same conversion is queued once.
+func (q *ConversionQueue) Enqueue(bookID, format string) error {
+	if q.converter(format) == nil {
+		return fmt.Errorf("ConversionQueue - Enqueue - %s: %w", format, ErrNoConverter)
+	}
+	job := ConversionJob{BookID: bookID, Format: format}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[job]; ok {
+		return nil
+	}
+	select {
+	case q.jobs <- job:
+		q.pending[job] = struct{}{}
+		return nil
+	default:
+		return fmt.Errorf("ConversionQueue - Enqueue - %w", ErrConversionFull)
+	}
+}
+
+// Run converts queued books with workers until context is done.
+func (q *ConversionQueue) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q.jobs:
+					book, err := q.Convert(ctx, job)
+					if err != nil {
+						q.logger.Error("ConversionQueue - Run - q.Convert: %s", err)
+					} else {
+						q.logger.Info("ConversionQueue - Run - converted %s to %s", book.ID, job.Format)
+					}
+					q.mu.Lock()
+					delete(q.pending, job)
+					q.mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Convert converts the first book file accepted by converter of the format.
+// Book, which already has the format, is returned as is.
+func (q *ConversionQueue) Convert(ctx context.Context, job ConversionJob) (entity.Book, error) {
+	c := q.converter(job.Format)
+	if c == nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - %s: %w", job.Format, ErrNoConverter)
+	}
+	book, err := q.shelf.ViewBook(ctx, job.BookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - q.shelf.ViewBook: %w", err)
+	}
+	source, err := q.source(book, c)
+	if errors.Is(err, entity.ErrBookAlreadyExists) {
+		return book, nil
+	}
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - %s: %w", book.ID, err)
+	}
+
+	_, input, err := q.shelf.DownloadBookFormat(ctx, book.ID, source.DocumentID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - q.shelf.DownloadBookFormat: %w", err)
+	}
+	input.Close()
+
+	dir, err := os.MkdirTemp("", "kompanion-convert-")
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - os.MkdirTemp: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// converters tell formats by extension, so both files are named after them
+	sourcePath := filepath.Join(dir, "source."+entity.FileExtension(source.Format))
+	err = copyFile(input.Name(), sourcePath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - copyFile: %w", err)
+	}
+	outputName := book.ID + "." + entity.FileExtension(job.Format)
+	outputPath := filepath.Join(dir, outputName)
+
+	convertCtx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	err = c.Convert(convertCtx, sourcePath, outputPath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - c.Convert: %w", err)
+	}
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - os.Open: %w", err)
+	}
+	defer output.Close()
+
+	book, err = q.shelf.AddBookFormat(ctx, book.ID, output, outputName)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("ConversionQueue - Convert - q.shelf.AddBookFormat: %w", err)
+	}
+	return book, nil
+}
+
+func (q *ConversionQueue) converter(format string) converter.Converter {
+	for _, c := range q.converters {
+		if c.Format() == format {
+			return c
+		}
+	}
+	return nil
+}
+
+// source returns the book file to convert, books with the format have none.
+func (q *ConversionQueue) source(book entity.Book, c converter.Converter) (entity.BookFormat, error) {
+	files := book.Files()
+	if slices.ContainsFunc(files, func(f entity.BookFormat) bool { return f.Format == c.Format() }) {
+		return entity.BookFormat{}, entity.ErrBookAlreadyExists
+	}
+	for _, file := range files {
+		if c.Accepts(file.Format) {
+			return file, nil
+		}
+	}
+	return entity.BookFormat{}, entity.ErrUnsupportedFormat
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,71 @@
+package library_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/converter"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// fakeConverter writes text version of the book, as a real converter would.
+const fakeConverter = `#!/bin/sh
+{ echo "Dune"; echo; cat "$1"; } > "$2"
+`
+
+func TestConversionQueue(t *testing.T) {
+	ctx := context.Background()
+	repo := &formatRepo{book: entity.Book{ID: "1", Title: "Dune", DocumentID: "epub_hash", FilePath: "1.epub"}}
+	store := storage.NewMemoryStorage()
+	shelf := library.NewBookShelf(store, repo, logger.New("error"))
+
+	dir := t.TempDir()
+	book := filepath.Join(dir, "1.epub")
+	require.NoError(t, os.WriteFile(book, []byte("A beginning is the time for taking the most delicate care.\n"), 0o644))
+	require.NoError(t, store.Write(ctx, book, "1.epub"))
+	script := filepath.Join(dir, "fake-convert")
+	require.NoError(t, os.WriteFile(script, []byte(fakeConverter), 0o755))
+
+	txt, err := converter.NewCommand("txt", []string{"epub"}, script+" {input} {output}")
+	require.NoError(t, err)
+	queue := library.NewConversionQueue(shelf, []converter.Converter{txt}, 1, time.Minute, logger.New("error"))
+
+	assert.Equal(t, []string{"txt"}, queue.Targets(repo.book))
+	assert.ErrorIs(t, queue.Enqueue("1", "mobi"), library.ErrNoConverter)
+
+	// the same job is queued once, others wait for free place
+	require.NoError(t, queue.Enqueue("1", "txt"))
+	require.NoError(t, queue.Enqueue("1", "txt"))
+	assert.ErrorIs(t, queue.Enqueue("2", "txt"), library.ErrConversionFull)
+	assert.Equal(t, []string{"txt"}, queue.Pending("1"))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go queue.Run(runCtx, 1)
+	require.Eventually(t, func() bool { return len(queue.Pending("1")) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, repo.formats, 1)
+	assert.Equal(t, "txt", repo.formats[0].Format)
+	converted, err := store.Read(ctx, repo.formats[0].FilePath)
+	require.NoError(t, err)
+	data, err := os.ReadFile(converted.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "delicate care")
+
+	// book with the format is not converted again
+	viewed, err := shelf.ViewBook(ctx, "1")
+	require.NoError(t, err)
+	assert.Empty(t, queue.Targets(viewed))
+	converted2, err := queue.Convert(ctx, library.ConversionJob{BookID: "1", Format: "txt"})
+	require.NoError(t, err)
+	assert.Len(t, converted2.Formats, 1)
+}
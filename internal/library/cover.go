@@ -0,0 +1,119 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+)
+
+const (
+	// covers are stored as JPEG, larger ones are scaled down to this width
+	maxCoverWidth  = 1200
+	maxCoverPixels = 50_000_000
+	coverQuality   = 90
+)
+
+// CoverSizes are widths of cover thumbnails, height keeps aspect ratio.
+var CoverSizes = map[string]int{
+	"small":  200,
+	"medium": 400,
+	"large":  800,
+}
+
+// normalizeCover validates JPEG, PNG or WebP image and re-encodes it as JPEG.
+func normalizeCover(data []byte) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", entity.ErrInvalidCover, err)
+	}
+	if format != "jpeg" && format != "png" && format != "webp" {
+		return nil, fmt.Errorf("%w: unsupported format %s", entity.ErrInvalidCover, format)
+	}
+	if config.Width == 0 || config.Height == 0 || config.Width*config.Height > maxCoverPixels {
+		return nil, fmt.Errorf("%w: bad size %dx%d", entity.ErrInvalidCover, config.Width, config.Height)
+	}
+	return resizeCover(data, maxCoverWidth)
+}
+
+// resizeCover scales image down to width, smaller images are only re-encoded.
+func resizeCover(data []byte, width int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", entity.ErrInvalidCover, err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() > width {
+		height := max(1, bounds.Dy()*width/bounds.Dx())
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+		img = scaled
+	}
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: coverQuality})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbnailPath is a storage path of cover thumbnail. Every cover has own path,
+// so thumbnails of replaced cover are never served.
+func thumbnailPath(coverPath string, width int) string {
+	name := strings.TrimSuffix(path.Base(coverPath), path.Ext(coverPath))
+	return fmt.Sprintf("covers/thumbnails/%s-%d.jpg", name, width)
+}
+
+// placeholderPath is a storage path of generated cover,
+// it changes with title and author shown on the cover.
+func placeholderPath(book entity.Book) string {
+	h := fnv.New32a()
+	h.Write([]byte(book.Title + "\x00" + book.Author))
+	return fmt.Sprintf("covers/placeholders/%s-%08x.jpg", book.ID, h.Sum32())
+}
+
+// bookCoverPath returns path of stored or generated cover.
+func bookCoverPath(book entity.Book) string {
+	if book.CoverPath != "" {
+		return book.CoverPath
+	}
+	return placeholderPath(book)
+}
+
+// writeTempFile writes data to closed temporary file, like storage returns files.
+func writeTempFile(data []byte) (*os.File, error) {
+	file, err := os.CreateTemp("", "cover")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// coverFiles lists cover and all its thumbnails.
+func coverFiles(coverPath string) []string {
+	if coverPath == "" {
+		return nil
+	}
+	files := []string{coverPath}
+	for _, width := range CoverSizes {
+		files = append(files, thumbnailPath(coverPath, width))
+	}
+	return files
+}
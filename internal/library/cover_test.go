@@ -0,0 +1,147 @@
+package library_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/placeholder"
+)
+
+// coverRepo keeps a single book and its cover path.
+type coverRepo struct {
+	library.BookRepo
+	book entity.Book
+}
+
+func (r *coverRepo) GetById(ctx context.Context, id string) (entity.Book, error) {
+	return r.book, nil
+}
+
+func (r *coverRepo) GetByFileHash(ctx context.Context, hash string) (entity.Book, error) {
+	return entity.Book{}, errors.New("no rows in result set")
+}
+
+func (r *coverRepo) Store(ctx context.Context, book entity.Book) error {
+	r.book = book
+	return nil
+}
+
+func (r *coverRepo) UpdateCover(ctx context.Context, id, coverPath string) error {
+	r.book.CoverPath = coverPath
+	return nil
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func decodeJPEG(t *testing.T, file *os.File) image.Config {
+	data, err := os.ReadFile(file.Name())
+	require.NoError(t, err)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	return config
+}
+
+func TestShelfUpdateCover(t *testing.T) {
+	ctx := context.Background()
+	repo := &coverRepo{book: entity.Book{ID: "1"}}
+	store := storage.NewMemoryStorage()
+	shelf := library.NewBookShelf(store, repo, logger.New("error"))
+
+	_, err := shelf.UpdateCover(ctx, "1", []byte("not an image"))
+	assert.ErrorIs(t, err, entity.ErrInvalidCover)
+
+	// large PNG is scaled down and stored as JPEG
+	book, err := shelf.UpdateCover(ctx, "1", encodePNG(t, 2400, 3600))
+	require.NoError(t, err)
+	cover, err := shelf.ViewCover(ctx, "1")
+	require.NoError(t, err)
+	config := decodeJPEG(t, cover)
+	assert.Equal(t, 1200, config.Width)
+	assert.Equal(t, 1800, config.Height)
+
+	thumbnail, err := shelf.ViewCoverThumbnail(ctx, "1", library.CoverSizes["small"])
+	require.NoError(t, err)
+	config = decodeJPEG(t, thumbnail)
+	assert.Equal(t, 200, config.Width)
+	assert.Equal(t, 300, config.Height)
+
+	// new cover removes previous one with thumbnails
+	_, err = shelf.UpdateCover(ctx, "1", encodePNG(t, 100, 150))
+	require.NoError(t, err)
+	_, err = store.Read(ctx, book.CoverPath)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	thumbnailPath := "covers/thumbnails/" + strings.TrimSuffix(strings.TrimPrefix(book.CoverPath, "covers/"), ".jpg") + "-200.jpg"
+	_, err = store.Read(ctx, thumbnailPath)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// small covers are not upscaled
+	thumbnail, err = shelf.ViewCoverThumbnail(ctx, "1", library.CoverSizes["large"])
+	require.NoError(t, err)
+	assert.Equal(t, 100, decodeJPEG(t, thumbnail).Width)
+}
+
+func TestShelfPlaceholderCover(t *testing.T) {
+	ctx := context.Background()
+	repo := &coverRepo{book: entity.Book{ID: "1", Title: "Dune", Author: "Frank Herbert"}}
+	shelf := library.NewBookShelf(storage.NewMemoryStorage(), repo, logger.New("error"))
+
+	cover, err := shelf.ViewCover(ctx, "1")
+	require.NoError(t, err)
+	config := decodeJPEG(t, cover)
+	assert.Equal(t, placeholder.Width, config.Width)
+	assert.Equal(t, placeholder.Height, config.Height)
+
+	thumbnail, err := shelf.ViewCoverThumbnail(ctx, "1", library.CoverSizes["small"])
+	require.NoError(t, err)
+	assert.Equal(t, 200, decodeJPEG(t, thumbnail).Width)
+}
+
+func TestShelfStoreBookPNGCover(t *testing.T) {
+	ctx := context.Background()
+	repo := &coverRepo{}
+	shelf := library.NewBookShelf(storage.NewMemoryStorage(), repo, logger.New("error"))
+
+	// comic pages are PNG, the first one becomes the cover
+	path := filepath.Join(t.TempDir(), "comic.cbz")
+	archive, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(archive)
+	page, err := w.Create("001.png")
+	require.NoError(t, err)
+	_, err = page.Write(encodePNG(t, 50, 80))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, archive.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	book, err := shelf.StoreBook(ctx, file, "comic.cbz")
+	require.NoError(t, err)
+	require.NotEmpty(t, book.CoverPath)
+
+	// cover is served as JPEG, like OPDS links declare
+	cover, err := shelf.ViewCover(ctx, book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50, decodeJPEG(t, cover).Width)
+}
@@ -0,0 +1,230 @@
+package library
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+)
+
+// Reasons, why books are considered duplicates.
+const (
+	DuplicateByISBN    = "isbn"
+	DuplicateByTitle   = "title"   // the same normalised title and author
+	DuplicateBySimilar = "similar" // similar title and author
+)
+
+const (
+	// books with similarity of title and author above the threshold are similar
+	titleSimilarity  = 0.65
+	authorSimilarity = 0.6
+)
+
+// DuplicateGroup is a cluster of books, which are probably the same book,
+// e.g. EPUB and PDF or two editions. Books are ordered from the oldest.
+type DuplicateGroup struct {
+	Books   []entity.Book
+	Reasons []string
+}
+
+// findDuplicates clusters books by ISBN, normalised title and author
+// and fuzzy similarity of both. Books without duplicates are skipped.
+func findDuplicates(books []entity.Book) []DuplicateGroup {
+	type key struct {
+		title, author string
+		numbers       string
+		trigrams      map[string]struct{}
+		authorGrams   map[string]struct{}
+	}
+	keys := make([]key, len(books))
+	for i, book := range books {
+		title, author := normalizeTitle(book.Title), normalizeAuthor(book.Author)
+		keys[i] = key{title, author, numbers(title), trigrams(title), trigrams(author)}
+	}
+
+	parent := make([]int, len(books))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reasons := make(map[[2]int]string)
+	union := func(i, j int, reason string) {
+		reasons[[2]int{i, j}] = reason
+		parent[find(j)] = find(i)
+	}
+
+	byISBN := make(map[string]int)
+	byTitle := make(map[string]int)
+	for i, book := range books {
+		if isbn := isbn13(book.ISBN); isbn != "" {
+			if j, ok := byISBN[isbn]; ok {
+				union(j, i, DuplicateByISBN)
+			} else {
+				byISBN[isbn] = i
+			}
+		}
+		if keys[i].title == "" {
+			continue
+		}
+		titleKey := keys[i].title + "\x00" + keys[i].author
+		if j, ok := byTitle[titleKey]; ok {
+			union(j, i, DuplicateByTitle)
+		} else {
+			byTitle[titleKey] = i
+		}
+	}
+
+	for i := range books {
+		for j := i + 1; j < len(books); j++ {
+			if find(i) == find(j) || keys[i].title == "" || keys[j].title == "" {
+				continue
+			}
+			// volumes of series differ only in number
+			if keys[i].numbers != keys[j].numbers {
+				continue
+			}
+			if similarity(keys[i].authorGrams, keys[j].authorGrams) < authorSimilarity {
+				continue
+			}
+			if similarity(keys[i].trigrams, keys[j].trigrams) >= titleSimilarity {
+				union(i, j, DuplicateBySimilar)
+			}
+		}
+	}
+
+	clusters := make(map[int]*DuplicateGroup)
+	roots := []int{}
+	for i, book := range books {
+		root := find(i)
+		if _, ok := clusters[root]; !ok {
+			clusters[root] = &DuplicateGroup{}
+			roots = append(roots, root)
+		}
+		clusters[root].Books = append(clusters[root].Books, book)
+	}
+	for pair, reason := range reasons {
+		group := clusters[find(pair[0])]
+		if !slices.Contains(group.Reasons, reason) {
+			group.Reasons = append(group.Reasons, reason)
+		}
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	for _, root := range roots {
+		group := clusters[root]
+		if len(group.Books) < 2 {
+			continue
+		}
+		sort.SliceStable(group.Books, func(i, j int) bool {
+			return group.Books[i].CreatedAt.Before(group.Books[j].CreatedAt)
+		})
+		sort.Strings(group.Reasons)
+		groups = append(groups, *group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Books[0].Title) < strings.ToLower(groups[j].Books[0].Title)
+	})
+	return groups
+}
+
+// normalizeText lowercases text, removes diacritics and punctuation.
+func normalizeText(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(text)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// normalizeTitle drops subtitle and notes like "(Illustrated edition)".
+func normalizeTitle(title string) string {
+	if i := strings.IndexAny(title, ":([{"); i > 0 {
+		title = title[:i]
+	}
+	return normalizeText(title)
+}
+
+// normalizeAuthor sorts name parts, so "Herbert, Frank" equals "Frank Herbert".
+func normalizeAuthor(author string) string {
+	parts := strings.Fields(normalizeText(author))
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// isbn13 returns ISBN-13 digits of ISBN-10 or ISBN-13, or empty string.
+func isbn13(isbn string) string {
+	var digits []byte
+	for _, r := range strings.ToUpper(isbn) {
+		if r >= '0' && r <= '9' || r == 'X' {
+			digits = append(digits, byte(r))
+		}
+	}
+	switch len(digits) {
+	case 13:
+		if slices.Contains(digits, 'X') {
+			return ""
+		}
+		return string(digits)
+	case 10:
+		digits = append([]byte("978"), digits[:9]...)
+		sum := 0
+		for i, d := range digits {
+			sum += int(d-'0') * (1 + 2*(i%2))
+		}
+		return string(append(digits, byte('0'+(10-sum%10)%10)))
+	}
+	return ""
+}
+
+// numbers returns numbers of text, like volume of the series.
+func numbers(text string) string {
+	var result []string
+	for _, word := range strings.Fields(text) {
+		if strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// trigrams returns set of character trigrams of words, as pg_trgm does.
+func trigrams(text string) map[string]struct{} {
+	grams := make(map[string]struct{})
+	for _, word := range strings.Fields(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return grams
+}
+
+// similarity is a share of common trigrams, empty sets are similar.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for gram := range a {
+		if _, ok := b[gram]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
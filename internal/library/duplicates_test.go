@@ -0,0 +1,128 @@
+package library_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// duplicateRepo keeps books in memory and records merges.
+type duplicateRepo struct {
+	library.BookRepo
+	books    []entity.Book
+	merged   map[string]string
+	updated  entity.Book
+	mergeErr error
+}
+
+func (r *duplicateRepo) ListAll(ctx context.Context) ([]entity.Book, error) {
+	return r.books, nil
+}
+
+func (r *duplicateRepo) GetById(ctx context.Context, id string) (entity.Book, error) {
+	for _, book := range r.books {
+		if book.ID == id {
+			return book, nil
+		}
+	}
+	return entity.Book{}, assert.AnError
+}
+
+func (r *duplicateRepo) Merge(ctx context.Context, book entity.Book, duplicates []entity.Book) error {
+	if r.mergeErr != nil {
+		return r.mergeErr
+	}
+	for _, duplicate := range duplicates {
+		r.merged[duplicate.ID] = book.ID
+	}
+	r.updated = book
+	return nil
+}
+
+func TestShelfFindDuplicates(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &duplicateRepo{books: []entity.Book{
+		{ID: "1", Title: "Dune", Author: "Frank Herbert", FilePath: "1.epub", CreatedAt: day},
+		{ID: "2", Title: "Dune: Deluxe Edition", Author: "Herbert, Frank", FilePath: "2.pdf", CreatedAt: day.Add(time.Hour)},
+		{ID: "3", Title: "The Hobbit", Author: "J. R. R. Tolkien", ISBN: "0-261-10221-4", CreatedAt: day},
+		{ID: "4", Title: "Hobbit, or There and Back Again", Author: "Tolkien", ISBN: "978-0261102217", CreatedAt: day.Add(time.Hour)},
+		{ID: "5", Title: "Crime and Punishment", Author: "Fyodor Dostoyevsky", CreatedAt: day},
+		{ID: "6", Title: "Crime and Punishmnet", Author: "Fyodor Dostoevsky", CreatedAt: day.Add(time.Hour)},
+		{ID: "7", Title: "Children of Dune", Author: "Frank Herbert", CreatedAt: day},
+		{ID: "8", Title: "Foundation 1", Author: "Isaac Asimov", CreatedAt: day},
+		{ID: "9", Title: "Foundation 2", Author: "Isaac Asimov", CreatedAt: day},
+	}}
+	shelf := library.NewBookShelf(storage.NewMemoryStorage(), repo, logger.New("error"))
+
+	groups, err := shelf.FindDuplicates(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+
+	ids := func(group library.DuplicateGroup) []string {
+		result := []string{}
+		for _, book := range group.Books {
+			result = append(result, book.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"5", "6"}, ids(groups[0]))
+	assert.Equal(t, []string{library.DuplicateBySimilar}, groups[0].Reasons)
+	assert.Equal(t, []string{"1", "2"}, ids(groups[1]))
+	assert.Equal(t, []string{library.DuplicateByTitle}, groups[1].Reasons)
+	assert.Equal(t, []string{"3", "4"}, ids(groups[2]))
+	assert.Equal(t, []string{library.DuplicateByISBN}, groups[2].Reasons)
+}
+
+func TestShelfMergeBooks(t *testing.T) {
+	repo := &duplicateRepo{
+		books: []entity.Book{
+			{ID: "1", Title: "Dune", Author: "Frank Herbert", FilePath: "1.epub"},
+			{ID: "2", Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Year: 1965, FilePath: "2.pdf", CoverPath: "covers/2.jpg"},
+		},
+		merged: map[string]string{},
+	}
+	shelf := library.NewBookShelf(storage.NewMemoryStorage(), repo, logger.New("error"))
+
+	book, err := shelf.MergeBooks(context.Background(), "1", []string{"1", "2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"2": "1"}, repo.merged)
+	assert.Equal(t, "1", book.ID)
+	assert.Equal(t, "9780441172719", book.ISBN)
+	assert.Equal(t, 1965, book.Year)
+	assert.Equal(t, "covers/2.jpg", book.CoverPath)
+	assert.Equal(t, "1.epub", repo.updated.FilePath)
+}
+
+func TestShelfMergeBooksFailure(t *testing.T) {
+	repo := &duplicateRepo{
+		books: []entity.Book{
+			{ID: "1", Title: "Dune", Author: "Frank Herbert", FilePath: "1.epub"},
+			{ID: "2", Title: "Dune", Author: "Frank Herbert", FilePath: "2.pdf", CoverPath: "covers/2.jpg"},
+			{ID: "3", Title: "Dune", Author: "Frank Herbert", FilePath: "3.fb2", CoverPath: "covers/3.jpg"},
+		},
+		merged:   map[string]string{},
+		mergeErr: assert.AnError,
+	}
+	bookStorage := storage.NewMemoryStorage()
+	cover := filepath.Join(t.TempDir(), "cover")
+	require.NoError(t, os.WriteFile(cover, []byte("cover"), 0o644))
+	require.NoError(t, bookStorage.Write(context.Background(), cover, "covers/3.jpg"))
+	shelf := library.NewBookShelf(bookStorage, repo, logger.New("error"))
+
+	_, err := shelf.MergeBooks(context.Background(), "1", []string{"2", "3"})
+	require.ErrorIs(t, err, assert.AnError)
+	assert.Empty(t, repo.merged)
+	// cover of duplicate is kept, when nothing is merged
+	_, err = bookStorage.Read(context.Background(), "covers/3.jpg")
+	assert.NoError(t, err)
+}
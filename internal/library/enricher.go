@@ -0,0 +1,197 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/metadata"
+)
+
+// CoverField is a name of the cover in selected fields of Apply.
+const CoverField = "cover"
+
+// Suggestion is metadata found by one provider for the book.
+type Suggestion struct {
+	Provider string
+	Metadata metadata.Metadata
+	Changes  []FieldChange
+}
+
+// FieldChange is a field, which value differs from the book one.
+type FieldChange struct {
+	Field     string // name of the field in edit form
+	Label     string
+	Current   string
+	Suggested string
+}
+
+// Enricher looks up book metadata in online catalogs,
+// the user picks fields to apply to the book.
+type Enricher struct {
+	shelf     Shelf
+	providers []metadata.Provider
+	logger    logger.Interface
+}
+
+func NewEnricher(shelf Shelf, providers []metadata.Provider, l logger.Interface) *Enricher {
+	return &Enricher{
+		shelf:     shelf,
+		providers: providers,
+		logger:    l,
+	}
+}
+
+// Suggest asks every provider for the book, providers without match are skipped.
+func (e *Enricher) Suggest(ctx context.Context, bookID string) (entity.Book, []Suggestion, error) {
+	book, err := e.shelf.ViewBook(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, nil, fmt.Errorf("Enricher - Suggest - e.shelf.ViewBook: %w", err)
+	}
+
+	var suggestions []Suggestion
+	for _, provider := range e.providers {
+		m, err := lookup(ctx, provider, book)
+		if errors.Is(err, metadata.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			e.logger.Error("Enricher - Suggest - %s: %s", provider.Name(), err)
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{
+			Provider: provider.Name(),
+			Metadata: m,
+			Changes:  diffMetadata(book, m),
+		})
+	}
+	return book, suggestions, nil
+}
+
+// Apply looks up the book again with the provider and updates selected fields,
+// CoverField replaces the cover.
+func (e *Enricher) Apply(ctx context.Context, bookID, providerName string, fields []string) (entity.Book, error) {
+	idx := slices.IndexFunc(e.providers, func(p metadata.Provider) bool { return p.Name() == providerName })
+	if idx < 0 {
+		return entity.Book{}, fmt.Errorf("Enricher - Apply - unknown provider: %s", providerName)
+	}
+	book, err := e.shelf.ViewBook(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("Enricher - Apply - e.shelf.ViewBook: %w", err)
+	}
+	m, err := lookup(ctx, e.providers[idx], book)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("Enricher - Apply - lookup: %w", err)
+	}
+
+	// empty fields are kept by UpdateBookMetadata
+	suggested := metadataToBook(m)
+	var update entity.Book
+	for _, field := range fields {
+		switch field {
+		case "title":
+			update.Title = suggested.Title
+		case "author":
+			update.Author = suggested.Author
+		case "publisher":
+			update.Publisher = suggested.Publisher
+		case "year":
+			update.Year = suggested.Year
+		case "isbn":
+			update.ISBN = suggested.ISBN
+		case "language":
+			update.Language = suggested.Language
+		case "series":
+			update.Series = suggested.Series
+		case "series_index":
+			update.SeriesIndex = suggested.SeriesIndex
+		case "subjects":
+			update.Subjects = suggested.Subjects
+		case "description":
+			update.Description = suggested.Description
+		}
+	}
+
+	book, err = e.shelf.UpdateBookMetadata(ctx, book.ID, update)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("Enricher - Apply - e.shelf.UpdateBookMetadata: %w", err)
+	}
+	if slices.Contains(fields, CoverField) && len(m.Cover) > 0 {
+		book, err = e.shelf.UpdateCover(ctx, book.ID, m.Cover)
+		if err != nil {
+			return entity.Book{}, fmt.Errorf("Enricher - Apply - e.shelf.UpdateCover: %w", err)
+		}
+	}
+	return book, nil
+}
+
+// lookup searches by ISBN first, then by title and author.
+func lookup(ctx context.Context, provider metadata.Provider, book entity.Book) (metadata.Metadata, error) {
+	if book.ISBN != "" {
+		m, err := provider.Lookup(ctx, metadata.Query{ISBN: book.ISBN})
+		if !errors.Is(err, metadata.ErrNotFound) {
+			return m, err
+		}
+	}
+	if book.Title == "" {
+		return metadata.Metadata{}, metadata.ErrNotFound
+	}
+	return provider.Lookup(ctx, metadata.Query{Title: book.Title, Author: book.Author})
+}
+
+func metadataToBook(m metadata.Metadata) entity.Book {
+	return entity.Book{
+		Title:       m.Title,
+		Author:      m.Author,
+		Publisher:   m.Publisher,
+		Year:        m.Year(),
+		ISBN:        m.ISBN,
+		Language:    m.Language,
+		Series:      m.Series,
+		SeriesIndex: m.SeriesIndex,
+		Subjects:    m.Subjects,
+		Description: m.Description,
+	}
+}
+
+// diffMetadata lists fields, where provider has a different non-empty value.
+func diffMetadata(book entity.Book, m metadata.Metadata) []FieldChange {
+	suggested := metadataToBook(m)
+	formatIndex := func(index float64) string {
+		if index == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(index, 'f', -1, 64)
+	}
+	formatYear := func(year int) string {
+		if year == 0 {
+			return ""
+		}
+		return strconv.Itoa(year)
+	}
+
+	fields := []FieldChange{
+		{"title", "Title", book.Title, suggested.Title},
+		{"author", "Author", book.Author, suggested.Author},
+		{"isbn", "ISBN", book.ISBN, suggested.ISBN},
+		{"language", "Language", book.Language, suggested.Language},
+		{"series", "Series", book.Series, suggested.Series},
+		{"series_index", "Number in series", formatIndex(book.SeriesIndex), formatIndex(suggested.SeriesIndex)},
+		{"subjects", "Subjects", strings.Join(book.Subjects, ", "), strings.Join(suggested.Subjects, ", ")},
+		{"year", "Year", formatYear(book.Year), formatYear(suggested.Year)},
+		{"publisher", "Publisher", book.Publisher, suggested.Publisher},
+		{"description", "Description", book.Description, suggested.Description},
+	}
+	var changes []FieldChange
+	for _, field := range fields {
+		if field.Suggested != "" && field.Suggested != field.Current {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,86 @@
+package library_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/metadata"
+)
+
+// stubProvider knows books by ISBN and by title.
+type stubProvider map[string]metadata.Metadata
+
+func (p stubProvider) Name() string {
+	return "stub"
+}
+
+func (p stubProvider) Lookup(ctx context.Context, query metadata.Query) (metadata.Metadata, error) {
+	m, ok := p[query.ISBN+query.Title]
+	if !ok {
+		return metadata.Metadata{}, metadata.ErrNotFound
+	}
+	return m, nil
+}
+
+// editShelf keeps a single book and records updates.
+type editShelf struct {
+	library.Shelf
+	book   entity.Book
+	update entity.Book
+	cover  []byte
+}
+
+func (s *editShelf) ViewBook(ctx context.Context, bookID string) (entity.Book, error) {
+	return s.book, nil
+}
+
+func (s *editShelf) UpdateBookMetadata(ctx context.Context, bookID string, metadata entity.Book) (entity.Book, error) {
+	s.update = metadata
+	return s.book, nil
+}
+
+func (s *editShelf) UpdateCover(ctx context.Context, bookID string, cover []byte) (entity.Book, error) {
+	s.cover = cover
+	return s.book, nil
+}
+
+func TestEnricherSuggestAndApply(t *testing.T) {
+	shelf := &editShelf{book: entity.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", ISBN: "123"}}
+	provider := stubProvider{
+		// unknown ISBN falls back to title search
+		"Dune": {
+			Title:     "Dune",
+			Author:    "Frank Herbert",
+			Publisher: "Ace",
+			Date:      "1990-09-01",
+			ISBN:      "9780441172719",
+			Cover:     []byte("cover"),
+		},
+	}
+	enricher := library.NewEnricher(shelf, []metadata.Provider{provider}, logger.New("error"))
+
+	book, suggestions, err := enricher.Suggest(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, shelf.book, book)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "stub", suggestions[0].Provider)
+	assert.Equal(t, []library.FieldChange{
+		{Field: "isbn", Label: "ISBN", Current: "123", Suggested: "9780441172719"},
+		{Field: "year", Label: "Year", Current: "", Suggested: "1990"},
+		{Field: "publisher", Label: "Publisher", Current: "", Suggested: "Ace"},
+	}, suggestions[0].Changes)
+
+	_, err = enricher.Apply(context.Background(), "1", "stub", []string{"year", "publisher", library.CoverField})
+	require.NoError(t, err)
+	assert.Equal(t, entity.Book{Publisher: "Ace", Year: 1990}, shelf.update)
+	assert.Equal(t, []byte("cover"), shelf.cover)
+
+	_, err = enricher.Apply(context.Background(), "1", "unknown", []string{"year"})
+	assert.Error(t, err)
+}
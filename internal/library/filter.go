@@ -0,0 +1,144 @@
+package library
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fields that books can be grouped by for navigation.
+const (
+	GroupByAuthor    = "author"
+	GroupBySeries    = "series"
+	GroupByPublisher = "publisher"
+	GroupByLanguage  = "language"
+)
+
+// Reading statuses, based on the latest synced progress of the book.
+const (
+	StatusReading  = "reading"
+	StatusFinished = "finished"
+	StatusUnread   = "unread"
+)
+
+// progressJoin attaches the latest synced progress to every book
+// as progress_percentage and progress_at columns.
+const progressJoin = `
+	LEFT JOIN LATERAL (
+		SELECT sp.percentage AS progress_percentage, sp.created_at AS progress_at
+		FROM sync_progress sp
+		WHERE sp.koreader_partial_md5 IN (SELECT library_book_all_hashes(library_book.koreader_partial_md5))
+		ORDER BY sp.created_at DESC
+		LIMIT 1
+	) progress ON TRUE`
+
+// fileFormat is the format of the main file by its extension,
+// the same way as entity.Book.FileFormat.
+const fileFormat = `CASE
+		WHEN storage_file_path LIKE '%.fb2.zip' THEN 'fb2.zip'
+		WHEN storage_file_path LIKE '%.kepub.epub' THEN 'kepub'
+		ELSE substring(storage_file_path FROM '\.([^.]+)$')
+	END`
+
+// Formats are file formats books are stored in.
+var Formats = []string{
+	"epub", "kepub", "pdf", "fb2", "fb2.zip", "mobi", "azw3", "cbz", "cbr",
+	"djvu", "txt", "md", "html", "rtf", "docx",
+}
+
+// BookGroup is a distinct value of a grouping field with number of books.
+type BookGroup struct {
+	Value string
+	Count int
+}
+
+// BookFilter narrows down list of books, empty fields are ignored.
+type BookFilter struct {
+	Query      string // search terms, matched like in SearchBooks
+	Author     string
+	Series     string
+	Publisher  string
+	Language   string
+	Format     string // one of Formats, other values are ignored
+	Status     string
+	Collection string // collection id
+}
+
+// FilterByGroup returns filter that matches books with given value of group field.
+func FilterByGroup(field, value string) (BookFilter, error) {
+	switch field {
+	case GroupByAuthor:
+		return BookFilter{Author: value}, nil
+	case GroupBySeries:
+		return BookFilter{Series: value}, nil
+	case GroupByPublisher:
+		return BookFilter{Publisher: value}, nil
+	case GroupByLanguage:
+		return BookFilter{Language: value}, nil
+	}
+	return BookFilter{}, fmt.Errorf("unknown group field: %s", field)
+}
+
+func isGroupField(field string) bool {
+	switch field {
+	case GroupByAuthor, GroupBySeries, GroupByPublisher, GroupByLanguage:
+		return true
+	}
+	return false
+}
+
+// where builds sql condition with positional arguments for the filter.
+func (f BookFilter) where() (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	add := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	add("author", f.Author)
+	add("series", f.Series)
+	add("publisher", f.Publisher)
+	add("language", f.Language)
+	if query := strings.TrimSpace(f.Query); query != "" {
+		args = append(args, query)
+		conditions = append(conditions, searchConditionFor(len(args)))
+	}
+	if slices.Contains(Formats, f.Format) {
+		// main file or any additional file of the book
+		args = append(args, f.Format)
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s = $%d OR EXISTS (SELECT 1 FROM library_book_format WHERE library_book_format.library_book_id = library_book.id AND library_book_format.format = $%d))",
+			fileFormat, len(args), len(args)))
+	}
+	if f.Collection != "" {
+		args = append(args, f.Collection)
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT library_book_id FROM library_collection_book WHERE library_collection_id = $%d)", len(args)))
+	}
+	switch f.Status {
+	case StatusReading:
+		conditions = append(conditions, "progress_percentage > 0 AND progress_percentage < 1")
+	case StatusFinished:
+		conditions = append(conditions, "progress_percentage >= 1")
+	case StatusUnread:
+		conditions = append(conditions, "coalesce(progress_percentage, 0) = 0")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// searchConditionFor returns searchCondition over n-th positional argument.
+func searchConditionFor(n int) string {
+	return strings.ReplaceAll(searchCondition, "$1", fmt.Sprintf("$%d", n))
+}
+
+// from returns source tables for the filter, progress is joined
+// only when status filter or last activity order needs it.
+func (f BookFilter) from(sortBy string) string {
+	if f.Status != "" || sortBy == "last_activity" {
+		return "library_book" + progressJoin
+	}
+	return "library_book"
+}
@@ -0,0 +1,145 @@
+package library_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// formatRepo keeps a single book and its additional formats.
+type formatRepo struct {
+	library.BookRepo
+	book       entity.Book
+	formats    []entity.BookFormat
+	replaceErr error
+}
+
+func (r *formatRepo) GetById(ctx context.Context, id string) (entity.Book, error) {
+	return r.book, nil
+}
+
+func (r *formatRepo) GetByFileHash(ctx context.Context, hash string) (entity.Book, error) {
+	for _, file := range append(r.book.Files(), r.formats...) {
+		if file.DocumentID == hash {
+			return r.book, nil
+		}
+	}
+	return entity.Book{}, errors.New("no rows in result set")
+}
+
+func (r *formatRepo) ListFormats(ctx context.Context, ids []string) ([]entity.BookFormat, error) {
+	return r.formats, nil
+}
+
+func (r *formatRepo) StoreFormat(ctx context.Context, format entity.BookFormat) error {
+	r.formats = append(r.formats, format)
+	return nil
+}
+
+func (r *formatRepo) ReplaceFile(ctx context.Context, book entity.Book, previousHash string) error {
+	if r.replaceErr != nil {
+		return r.replaceErr
+	}
+	r.book = book
+	return nil
+}
+
+func (r *formatRepo) DeleteFormat(ctx context.Context, id, documentID string) error {
+	r.formats = r.formats[:0]
+	return nil
+}
+
+func TestShelfBookFormats(t *testing.T) {
+	ctx := context.Background()
+	repo := &formatRepo{book: entity.Book{ID: "1", Title: "Dune", DocumentID: "epub_hash", FilePath: "1.epub"}}
+	store := storage.NewMemoryStorage()
+	shelf := library.NewBookShelf(store, repo, logger.New("error"))
+
+	path := filepath.Join(t.TempDir(), "dune.txt")
+	require.NoError(t, os.WriteFile(path, []byte("Dune\n\nA beginning is the time for taking the most delicate care."), 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	book, err := shelf.AddBookFormat(ctx, "1", file, "dune.txt")
+	require.NoError(t, err)
+	files := book.Files()
+	require.Len(t, files, 2)
+	assert.Equal(t, "epub", files[0].Format)
+	assert.Equal(t, "txt", files[1].Format)
+	assert.Equal(t, "text/plain", files[1].MimeType())
+
+	// the same file can not be added twice
+	_, err = shelf.AddBookFormat(ctx, "1", file, "dune.txt")
+	assert.ErrorIs(t, err, entity.ErrBookAlreadyExists)
+
+	// additional format can not become the main file as well
+	_, err = shelf.ReplaceBookFile(ctx, "1", file, "dune.txt")
+	assert.ErrorIs(t, err, entity.ErrBookAlreadyExists)
+
+	book, err = shelf.ViewBook(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, book.Formats, 1)
+	documentID := book.Formats[0].DocumentID
+	assert.Equal(t, "epub_hash", book.DocumentID)
+
+	downloaded, stored, err := shelf.DownloadBookFormat(ctx, "1", documentID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(downloaded.Filename(), ".txt"))
+	assert.Equal(t, documentID, downloaded.DocumentID)
+	data, err := os.ReadFile(stored.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "delicate care")
+
+	_, _, err = shelf.DownloadBookFormat(ctx, "1", "unknown")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, shelf.DeleteBookFormat(ctx, "1", documentID))
+	_, err = store.Read(ctx, book.Formats[0].FilePath)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// writeLog remembers paths written to the storage.
+type writeLog struct {
+	storage.Storage
+	written []string
+}
+
+func (s *writeLog) Write(ctx context.Context, source string, filepath string) error {
+	s.written = append(s.written, filepath)
+	return s.Storage.Write(ctx, source, filepath)
+}
+
+func TestShelfReplaceBookFileFailure(t *testing.T) {
+	ctx := context.Background()
+	repo := &formatRepo{
+		book:       entity.Book{ID: "1", Title: "Dune", DocumentID: "epub_hash", FilePath: "1.epub"},
+		replaceErr: errors.New("connection reset"),
+	}
+	store := &writeLog{Storage: storage.NewMemoryStorage()}
+	shelf := library.NewBookShelf(store, repo, logger.New("error"))
+
+	path := filepath.Join(t.TempDir(), "dune.txt")
+	require.NoError(t, os.WriteFile(path, []byte("Dune\n\nA beginning is the time for taking the most delicate care."), 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = shelf.ReplaceBookFile(ctx, "1", file, "dune.txt")
+	require.Error(t, err)
+	// new file is removed from storage, because nothing points to it
+	require.Len(t, store.written, 1)
+	_, err = store.Read(ctx, store.written[0])
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
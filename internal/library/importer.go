@@ -0,0 +1,190 @@
+package library
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// Import statuses of a single file.
+const (
+	ImportStored    = "stored"
+	ImportDuplicate = "duplicate"
+	ImportSkipped   = "skipped"
+	ImportFailed    = "failed"
+)
+
+// ImportResult is an outcome of importing one file.
+type ImportResult struct {
+	Name   string
+	Status string
+	Book   entity.Book
+	Err    error
+}
+
+// ImportReport collects results of a bulk import.
+type ImportReport struct {
+	Results []ImportResult
+}
+
+func (r *ImportReport) add(results ...ImportResult) {
+	r.Results = append(r.Results, results...)
+}
+
+// Count returns number of files with the status.
+func (r ImportReport) Count(status string) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// String is a one line summary of the report.
+func (r ImportReport) String() string {
+	return fmt.Sprintf("stored: %d, duplicates: %d, skipped: %d, failed: %d",
+		r.Count(ImportStored), r.Count(ImportDuplicate), r.Count(ImportSkipped), r.Count(ImportFailed))
+}
+
+// Importer stores many books at once from directories and ZIP archives.
+type Importer struct {
+	shelf  Shelf
+	logger logger.Interface
+}
+
+func NewImporter(shelf Shelf, l logger.Interface) *Importer {
+	return &Importer{
+		shelf:  shelf,
+		logger: l,
+	}
+}
+
+// ImportDir walks directory recursively and imports every file,
+// ZIP archives are unpacked. Hidden files and directories are ignored.
+func (i *Importer) ImportDir(ctx context.Context, dir string) (ImportReport, error) {
+	var report ImportReport
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name, _ := filepath.Rel(dir, path)
+		report.add(i.Import(ctx, path, name)...)
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("Importer - ImportDir - filepath.WalkDir: %w", err)
+	}
+	return report, nil
+}
+
+// Import imports a single file or every file inside a ZIP archive.
+// ZIP archives, which are books themselves, like .fb2.zip, are stored as is.
+// name is shown in the report instead of path, e.g. uploaded filename.
+func (i *Importer) Import(ctx context.Context, path, name string) []ImportResult {
+	result := i.importFile(ctx, path, name)
+	if result.Status == ImportSkipped && strings.EqualFold(filepath.Ext(name), ".zip") {
+		return i.importArchive(ctx, path, name)
+	}
+	return []ImportResult{result}
+}
+
+func (i *Importer) importArchive(ctx context.Context, path, name string) []ImportResult {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return []ImportResult{{Name: name, Status: ImportFailed, Err: err}}
+	}
+	defer archive.Close()
+
+	results := make([]ImportResult, 0, len(archive.File))
+	for _, f := range archive.File {
+		if ctx.Err() != nil {
+			break
+		}
+		if f.FileInfo().IsDir() || strings.HasPrefix(filepath.Base(f.Name), ".") {
+			continue
+		}
+		entryName := name + "/" + f.Name
+		tempPath, err := extractZipEntry(f)
+		if err != nil {
+			results = append(results, ImportResult{Name: entryName, Status: ImportFailed, Err: err})
+			continue
+		}
+		results = append(results, i.importFile(ctx, tempPath, entryName))
+		os.Remove(tempPath)
+	}
+	return results
+}
+
+func (i *Importer) importFile(ctx context.Context, path, name string) (result ImportResult) {
+	// parsers of untrusted files may panic, it must not stop the whole import
+	defer func() {
+		if r := recover(); r != nil {
+			i.logger.Error("Importer - panic while importing %s: %v", name, r)
+			result = ImportResult{Name: name, Status: ImportFailed, Err: fmt.Errorf("panic: %v", r)}
+		}
+	}()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ImportResult{Name: name, Status: ImportFailed, Err: err}
+	}
+	defer file.Close()
+
+	book, err := i.shelf.StoreBook(ctx, file, filepath.Base(name))
+	switch {
+	case err == nil:
+		i.logger.Info("Importer - stored %s as %s", name, book.ID)
+		return ImportResult{Name: name, Status: ImportStored, Book: book}
+	case errors.Is(err, entity.ErrBookAlreadyExists):
+		return ImportResult{Name: name, Status: ImportDuplicate, Book: book, Err: err}
+	case errors.Is(err, entity.ErrUnsupportedFormat):
+		return ImportResult{Name: name, Status: ImportSkipped, Err: err}
+	default:
+		i.logger.Error("Importer - failed to import %s: %s", name, err)
+		return ImportResult{Name: name, Status: ImportFailed, Err: err}
+	}
+}
+
+func extractZipEntry(f *zip.File) (string, error) {
+	src, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "import")
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
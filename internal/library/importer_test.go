@@ -0,0 +1,101 @@
+package library_test
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// contentShelf decides outcome of StoreBook by file content,
+// archives named .fb2.zip are books themselves.
+type contentShelf struct {
+	library.Shelf
+}
+
+func (s contentShelf) StoreBook(ctx context.Context, tempFile *os.File, uploadedFilename string) (entity.Book, error) {
+	data, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		return entity.Book{}, err
+	}
+	if strings.HasSuffix(uploadedFilename, ".fb2.zip") {
+		return entity.Book{ID: uploadedFilename}, nil
+	}
+	switch string(data) {
+	case "book":
+		return entity.Book{ID: uploadedFilename}, nil
+	case "duplicate":
+		return entity.Book{ID: "existing"}, entity.ErrBookAlreadyExists
+	case "panic":
+		panic("broken parser")
+	default:
+		return entity.Book{}, entity.ErrUnsupportedFormat
+	}
+}
+
+func TestImporterImportDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.epub":           "book",
+		"nested/b.epub":    "duplicate",
+		"notes.txt":        "text",
+		".hidden/c.epub":   "book",
+		"nested/.DS_Store": "junk",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeZip(t, filepath.Join(dir, "collection.zip"), map[string]string{"d.fb2": "book", "e.fb2": "duplicate"})
+	writeZip(t, filepath.Join(dir, "f.fb2.zip"), map[string]string{"f.fb2": "duplicate"})
+
+	importer := library.NewImporter(contentShelf{}, logger.New("error"))
+	report, err := importer.ImportDir(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(report.Results) != 6 {
+		t.Fatalf("expected 6 results, got %d: %v", len(report.Results), report.Results)
+	}
+	expected := map[string]int{
+		library.ImportStored:    3,
+		library.ImportDuplicate: 2,
+		library.ImportSkipped:   1,
+		library.ImportFailed:    0,
+	}
+	for status, count := range expected {
+		if report.Count(status) != count {
+			t.Errorf("expected %d %s, got %d", count, status, report.Count(status))
+		}
+	}
+	if report.String() != "stored: 3, duplicates: 2, skipped: 1, failed: 0" {
+		t.Errorf("unexpected summary: %s", report.String())
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	archive, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	zw := zip.NewWriter(archive)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+}
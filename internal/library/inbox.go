@@ -0,0 +1,151 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+const (
+	InboxProcessedDir = "processed"
+	InboxFailedDir    = "failed"
+)
+
+// inboxFile is a state of file seen on previous scan.
+type inboxFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// Inbox imports files dropped into a directory.
+// Only top-level files are imported, when they stay unchanged
+// between two scans, so files which are still copied are not touched.
+// Imported files are moved to processed/, others to failed/
+// with an error note next to them.
+type Inbox struct {
+	dir      string
+	importer *Importer
+	logger   logger.Interface
+	seen     map[string]inboxFile
+}
+
+func NewInbox(dir string, importer *Importer, l logger.Interface) (*Inbox, error) {
+	for _, sub := range []string{InboxProcessedDir, InboxFailedDir} {
+		err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm)
+		if err != nil {
+			return nil, fmt.Errorf("Inbox - NewInbox - os.MkdirAll: %w", err)
+		}
+	}
+	return &Inbox{
+		dir:      dir,
+		importer: importer,
+		logger:   l,
+		seen:     make(map[string]inboxFile),
+	}, nil
+}
+
+// Run scans inbox with interval until context is done.
+func (i *Inbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := i.Scan(ctx)
+		if err != nil {
+			i.logger.Error("Inbox - Run - i.Scan: %s", err)
+		} else if len(report.Results) > 0 {
+			i.logger.Info("Inbox - Run - %s", report.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan imports files, which did not change since previous scan.
+func (i *Inbox) Scan(ctx context.Context) (ImportReport, error) {
+	var report ImportReport
+
+	entries, err := os.ReadDir(i.dir)
+	if err != nil {
+		return report, fmt.Errorf("Inbox - Scan - os.ReadDir: %w", err)
+	}
+
+	seen := make(map[string]inboxFile, len(entries))
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		current := inboxFile{size: info.Size(), modTime: info.ModTime()}
+		if previous, ok := i.seen[entry.Name()]; !ok || previous != current {
+			seen[entry.Name()] = current
+			continue
+		}
+
+		results := i.importer.Import(ctx, filepath.Join(i.dir, entry.Name()), entry.Name())
+		report.add(results...)
+		err = i.moveImported(entry.Name(), results)
+		if err != nil {
+			i.logger.Error("Inbox - Scan - i.moveImported: %s", err)
+		}
+	}
+	i.seen = seen
+
+	return report, nil
+}
+
+// moveImported moves file out of inbox, failed files get a note with errors.
+func (i *Inbox) moveImported(name string, results []ImportResult) error {
+	var notes []string
+	for _, result := range results {
+		if result.Status == ImportSkipped || result.Status == ImportFailed {
+			notes = append(notes, fmt.Sprintf("%s: %s: %v", result.Name, result.Status, result.Err))
+		}
+	}
+	if len(results) == 0 {
+		notes = append(notes, name+": no books found")
+	}
+
+	sub := InboxProcessedDir
+	if len(notes) > 0 {
+		sub = InboxFailedDir
+	}
+	dest := uniquePath(filepath.Join(i.dir, sub, name))
+	err := os.Rename(filepath.Join(i.dir, name), dest)
+	if err != nil {
+		return fmt.Errorf("Inbox - moveImported - os.Rename: %w", err)
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+
+	err = os.WriteFile(dest+".error.txt", []byte(strings.Join(notes, "\n")+"\n"), 0o644)
+	if err != nil {
+		return fmt.Errorf("Inbox - moveImported - os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+// uniquePath adds timestamp to the file name, if path is already taken.
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), time.Now().UnixNano(), ext)
+}
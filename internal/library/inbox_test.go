@@ -0,0 +1,61 @@
+package library_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+func TestInboxScan(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.New("error")
+	inbox, err := library.NewInbox(dir, library.NewImporter(contentShelf{}, l), l)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, content := range map[string]string{"a.epub": "book", "notes.txt": "text", "broken.epub": "panic"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// first scan only remembers files, they may be still copying
+	report, err := inbox.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Results) != 0 {
+		t.Fatalf("expected no results on first scan, got %v", report.Results)
+	}
+
+	report, err = inbox.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// panic of a parser fails only the file, which caused it
+	if report.Count(library.ImportStored) != 1 || report.Count(library.ImportSkipped) != 1 || report.Count(library.ImportFailed) != 1 {
+		t.Errorf("unexpected report: %s", report.String())
+	}
+
+	for _, path := range []string{
+		filepath.Join(dir, library.InboxProcessedDir, "a.epub"),
+		filepath.Join(dir, library.InboxFailedDir, "notes.txt"),
+		filepath.Join(dir, library.InboxFailedDir, "notes.txt.error.txt"),
+		filepath.Join(dir, library.InboxFailedDir, "broken.epub"),
+		filepath.Join(dir, library.InboxFailedDir, "broken.epub.error.txt"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to exist: %v", path, err)
+		}
+	}
+	for _, name := range []string{"a.epub", "notes.txt", "broken.epub"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be moved out of inbox", name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package library
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/vanadium23/kompanion/internal/entity"
 )
@@ -15,10 +16,45 @@ type (
 			sortBy, sortOrder string,
 			page, perPage int,
 		) (PaginatedBookList, error)
+		SearchBooks(ctx context.Context,
+			query string,
+			page, perPage int,
+		) (PaginatedBookList, error)
+		ListFilteredBooks(ctx context.Context,
+			filter BookFilter,
+			sortBy, sortOrder string,
+			page, perPage int,
+		) (PaginatedBookList, error)
+		ListGroups(ctx context.Context, field string) ([]BookGroup, error)
 		ViewBook(ctx context.Context, bookID string) (entity.Book, error)
 		DownloadBook(ctx context.Context, bookID string) (entity.Book, *os.File, error)
+		DownloadBookFormat(ctx context.Context, bookID, documentID string) (entity.Book, *os.File, error)
+		AddBookFormat(ctx context.Context, bookID string, tempFile *os.File, uploadedFilename string) (entity.Book, error)
+		DeleteBookFormat(ctx context.Context, bookID, documentID string) error
 		UpdateBookMetadata(ctx context.Context, bookID string, metadata entity.Book) (entity.Book, error)
 		ViewCover(ctx context.Context, bookID string) (*os.File, error)
+		ViewCoverThumbnail(ctx context.Context, bookID string, width int) (*os.File, error)
+		UpdateCover(ctx context.Context, bookID string, cover []byte) (entity.Book, error)
+		ReextractCover(ctx context.Context, bookID string) (entity.Book, error)
+		ReplaceBookFile(ctx context.Context, bookID string, tempFile *os.File, uploadedFilename string) (entity.Book, error)
+		DeleteBook(ctx context.Context, bookID string) error
+		RestoreBook(ctx context.Context, bookID string) error
+		ListTrash(ctx context.Context) ([]entity.Book, error)
+		PurgeTrash(ctx context.Context, retention time.Duration) (int, error)
+		FindDuplicates(ctx context.Context) ([]DuplicateGroup, error)
+		MergeBooks(ctx context.Context, bookID string, duplicateIDs []string) (entity.Book, error)
+	}
+
+	// Collections -.
+	Collections interface {
+		ListCollections(ctx context.Context) ([]entity.Collection, error)
+		ViewCollection(ctx context.Context, id string) (entity.Collection, error)
+		CreateCollection(ctx context.Context, name string) (entity.Collection, error)
+		RenameCollection(ctx context.Context, id, name string) (entity.Collection, error)
+		DeleteCollection(ctx context.Context, id string) error
+		AddBook(ctx context.Context, id, bookID string) error
+		RemoveBook(ctx context.Context, id, bookID string) error
+		ListBookCollections(ctx context.Context, bookID string) ([]entity.Collection, error)
 	}
 
 	// BookRepo -.
@@ -29,8 +65,43 @@ type (
 			page, perPage int,
 		) ([]entity.Book, error)
 		Count(ctx context.Context) (int, error)
+		Search(ctx context.Context,
+			query string,
+			page, perPage int,
+		) ([]entity.Book, error)
+		SearchCount(ctx context.Context, query string) (int, error)
+		ListFiltered(ctx context.Context,
+			filter BookFilter,
+			sortBy, sortOrder string,
+			page, perPage int,
+		) ([]entity.Book, error)
+		CountFiltered(ctx context.Context, filter BookFilter) (int, error)
+		ListGroups(ctx context.Context, field string) ([]BookGroup, error)
 		GetById(context.Context, string) (entity.Book, error)
 		GetByFileHash(context.Context, string) (entity.Book, error)
 		Update(context.Context, entity.Book) error
+		UpdateCover(ctx context.Context, id, coverPath string) error
+		ReplaceFile(ctx context.Context, book entity.Book, previousHash string) error
+		Delete(context.Context, string) error
+		Restore(context.Context, string) error
+		ListDeleted(context.Context) ([]entity.Book, error)
+		Purge(context.Context, string) error
+		ListAll(context.Context) ([]entity.Book, error)
+		Merge(ctx context.Context, book entity.Book, duplicates []entity.Book) error
+		ListFormats(ctx context.Context, ids []string) ([]entity.BookFormat, error)
+		StoreFormat(ctx context.Context, format entity.BookFormat) error
+		DeleteFormat(ctx context.Context, id, documentID string) error
+	}
+
+	// CollectionRepo -.
+	CollectionRepo interface {
+		Store(context.Context, entity.Collection) error
+		Update(context.Context, entity.Collection) error
+		Delete(context.Context, string) error
+		GetById(context.Context, string) (entity.Collection, error)
+		List(context.Context) ([]entity.Collection, error)
+		ListByBook(ctx context.Context, bookID string) ([]entity.Collection, error)
+		AddBook(ctx context.Context, id, bookID string) error
+		RemoveBook(ctx context.Context, id, bookID string) error
 	}
 )
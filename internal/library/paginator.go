@@ -55,3 +55,7 @@ func (p PaginatedBookList) Prev() int {
 	}
 	return p.currentPage
 }
+
+func (p PaginatedBookList) TotalCount() int {
+	return p.totalCount
+}
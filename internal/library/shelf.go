@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/moroz/uuidv7-go"
@@ -13,6 +15,7 @@ import (
 	"github.com/vanadium23/kompanion/internal/storage"
 	"github.com/vanadium23/kompanion/pkg/logger"
 	"github.com/vanadium23/kompanion/pkg/metadata"
+	"github.com/vanadium23/kompanion/pkg/placeholder"
 	"github.com/vanadium23/kompanion/pkg/utils"
 )
 
@@ -37,20 +40,27 @@ func (uc *BookShelf) StoreBook(ctx context.Context, tempFile *os.File, uploadedF
 	}
 	foundBook, err := uc.repo.GetByFileHash(ctx, koreaderPartialMD5)
 	if err == nil {
+		if foundBook.IsDeleted() {
+			err = uc.repo.Restore(ctx, foundBook.ID)
+			if err != nil {
+				return entity.Book{}, fmt.Errorf("BookShelf - StoreBook - s.repo.Restore: %w", err)
+			}
+			foundBook.DeletedAt = time.Time{}
+		}
 		return foundBook, entity.ErrBookAlreadyExists
 	}
 
-	m, err := metadata.ExtractBookMetadata(tempFile)
+	m, err := metadata.ExtractBookMetadata(tempFile, uploadedFilename)
 	if err != nil {
 		return entity.Book{}, fmt.Errorf("BookShelf - StoreBook - exractMetadata: %w", err)
 	}
 	if m.Format == "" {
-		return entity.Book{}, errors.New("BookShelf - StoreBook - unknown file format")
+		return entity.Book{}, fmt.Errorf("BookShelf - StoreBook - %w", entity.ErrUnsupportedFormat)
 	}
 
 	bookID := uuidv7.Generate()
 	createDate := time.Now()
-	storagepath := fmt.Sprintf("%s/%s.%s", createDate.Format("2006/01/02"), bookID, m.Format)
+	storagepath := fmt.Sprintf("%s/%s.%s", createDate.Format("2006/01/02"), bookID, entity.FileExtension(m.Format))
 
 	err = uc.storage.Write(ctx, tempFile.Name(), storagepath)
 	if err != nil {
@@ -58,24 +68,37 @@ func (uc *BookShelf) StoreBook(ctx context.Context, tempFile *os.File, uploadedF
 	}
 	uc.logger.Info("BookShelf - StoreBook - documentID: %s", koreaderPartialMD5)
 
-	coverPath, err := writeCover(ctx, uc.storage, m.Cover, bookID.String())
-	if err != nil {
-		uc.logger.Error("BookShelf - StoreBook - writeCover: %s", err)
+	// covers are served as JPEG, embedded PNG or WebP ones are re-encoded
+	var coverPath string
+	if len(m.Cover) > 0 {
+		cover, err := normalizeCover(m.Cover)
+		if err == nil {
+			coverPath, err = writeCover(ctx, uc.storage, cover, bookID.String())
+		}
+		if err != nil {
+			uc.logger.Error("BookShelf - StoreBook - writeCover: %s", err)
+		}
 	}
 
 	book := entity.Book{
-		ID:         bookID.String(),
-		Title:      m.Title,
-		Author:     m.Author,
-		Publisher:  m.Publisher,
-		Year:       0,
-		CreatedAt:  createDate,
-		UpdatedAt:  createDate,
-		ISBN:       m.ISBN,
-		DocumentID: koreaderPartialMD5,
-		FilePath:   storagepath,
-		Format:     m.Format,
-		CoverPath:  coverPath,
+		ID:          bookID.String(),
+		Title:       utils.If(m.Title != "", m.Title, titleFromFilename(uploadedFilename)),
+		Author:      m.Author,
+		Publisher:   m.Publisher,
+		Year:        m.Year(),
+		CreatedAt:   createDate,
+		UpdatedAt:   createDate,
+		ISBN:        m.ISBN,
+		Series:      m.Series,
+		SeriesIndex: m.SeriesIndex,
+		Subjects:    m.Subjects,
+		Pages:       m.Pages,
+		Language:    m.Language,
+		Description: m.Description,
+		DocumentID:  koreaderPartialMD5,
+		FilePath:    storagepath,
+		Format:      m.Format,
+		CoverPath:   coverPath,
 	}
 
 	// place in database
@@ -102,6 +125,11 @@ func (uc *BookShelf) ListBooks(ctx context.Context,
 		return PaginatedBookList{}, fmt.Errorf("BookShelf - ListBooks - s.repo.Count: %w", err)
 	}
 
+	err = uc.attachFormats(ctx, books)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - ListBooks - attachFormats: %w", err)
+	}
+
 	pbl := NewPaginatedBookList(
 		books,
 		perPage,
@@ -112,13 +140,97 @@ func (uc *BookShelf) ListBooks(ctx context.Context,
 	return pbl, nil
 }
 
+func (uc *BookShelf) ListFilteredBooks(ctx context.Context,
+	filter BookFilter,
+	sortBy, sortOrder string,
+	page, perPage int) (PaginatedBookList, error) {
+	books, err := uc.repo.ListFiltered(ctx, filter, sortBy, sortOrder, page, perPage)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - ListFilteredBooks - s.repo.ListFiltered: %w", err)
+	}
+
+	totalCount, err := uc.repo.CountFiltered(ctx, filter)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - ListFilteredBooks - s.repo.CountFiltered: %w", err)
+	}
+
+	err = uc.attachFormats(ctx, books)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - ListFilteredBooks - attachFormats: %w", err)
+	}
+
+	return NewPaginatedBookList(books, perPage, page, totalCount), nil
+}
+
+func (uc *BookShelf) ListGroups(ctx context.Context, field string) ([]BookGroup, error) {
+	groups, err := uc.repo.ListGroups(ctx, field)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ListGroups - s.repo.ListGroups: %w", err)
+	}
+	return groups, nil
+}
+
+func (uc *BookShelf) SearchBooks(ctx context.Context,
+	query string,
+	page, perPage int) (PaginatedBookList, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return NewPaginatedBookList([]entity.Book{}, perPage, page, 0), nil
+	}
+
+	books, err := uc.repo.Search(ctx, query, page, perPage)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - SearchBooks - s.repo.Search: %w", err)
+	}
+
+	totalCount, err := uc.repo.SearchCount(ctx, query)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - SearchBooks - s.repo.SearchCount: %w", err)
+	}
+
+	err = uc.attachFormats(ctx, books)
+	if err != nil {
+		return PaginatedBookList{}, fmt.Errorf("BookShelf - SearchBooks - attachFormats: %w", err)
+	}
+
+	return NewPaginatedBookList(books, perPage, page, totalCount), nil
+}
+
 func (uc *BookShelf) ViewBook(ctx context.Context, bookID string) (entity.Book, error) {
 	book, err := uc.repo.GetById(ctx, bookID)
 	if err != nil {
 		return entity.Book{}, fmt.Errorf("BookShelf - GetBook - s.repo.Get: %w", err)
 	}
 
-	return book, nil
+	books := []entity.Book{book}
+	err = uc.attachFormats(ctx, books)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - GetBook - attachFormats: %w", err)
+	}
+	return books[0], nil
+}
+
+// attachFormats loads additional files of books with a single query.
+func (uc *BookShelf) attachFormats(ctx context.Context, books []entity.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]string, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	formats, err := uc.repo.ListFormats(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range books {
+		for _, format := range formats {
+			if format.BookID == books[i].ID {
+				books[i].Formats = append(books[i].Formats, format)
+			}
+		}
+	}
+	return nil
 }
 
 func (uc *BookShelf) UpdateBookMetadata(ctx context.Context, bookID string, metadata entity.Book) (entity.Book, error) {
@@ -128,13 +240,18 @@ func (uc *BookShelf) UpdateBookMetadata(ctx context.Context, bookID string, meta
 	}
 
 	updatedBook := entity.Book{
-		ID:        book.ID,
-		Title:     utils.If(metadata.Title == "", book.Title, metadata.Title),
-		Author:    utils.If(metadata.Author == "", book.Author, metadata.Author),
-		Publisher: utils.If(metadata.Publisher == "", book.Publisher, metadata.Publisher),
-		Year:      utils.If(metadata.Year == 0, book.Year, metadata.Year),
-		ISBN:      utils.If(metadata.ISBN == "", book.ISBN, metadata.ISBN),
-		UpdatedAt: time.Now(),
+		ID:          book.ID,
+		Title:       utils.If(metadata.Title == "", book.Title, metadata.Title),
+		Author:      utils.If(metadata.Author == "", book.Author, metadata.Author),
+		Publisher:   utils.If(metadata.Publisher == "", book.Publisher, metadata.Publisher),
+		Year:        utils.If(metadata.Year == 0, book.Year, metadata.Year),
+		ISBN:        utils.If(metadata.ISBN == "", book.ISBN, metadata.ISBN),
+		Series:      utils.If(metadata.Series == "", book.Series, metadata.Series),
+		SeriesIndex: utils.If(metadata.SeriesIndex == 0, book.SeriesIndex, metadata.SeriesIndex),
+		Subjects:    utils.If(len(metadata.Subjects) == 0, book.Subjects, metadata.Subjects),
+		Language:    utils.If(metadata.Language == "", book.Language, metadata.Language),
+		Description: utils.If(metadata.Description == "", book.Description, metadata.Description),
+		UpdatedAt:   time.Now(),
 	}
 
 	err = uc.repo.Update(ctx, updatedBook)
@@ -142,6 +259,16 @@ func (uc *BookShelf) UpdateBookMetadata(ctx context.Context, bookID string, meta
 		return entity.Book{}, fmt.Errorf("BookShelf - UpdateBookMetadata - s.repo.Update: %w", err)
 	}
 
+	// placeholder shows title and author, so it is generated again
+	if book.CoverPath == "" && (book.Title != updatedBook.Title || book.Author != updatedBook.Author) {
+		for _, path := range coverFiles(placeholderPath(book)) {
+			err = uc.storage.Delete(ctx, path)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				uc.logger.Error("BookShelf - UpdateBookMetadata - s.storage.Delete: %s", err)
+			}
+		}
+	}
+
 	return updatedBook, nil
 }
 
@@ -157,44 +284,452 @@ func (uc *BookShelf) DownloadBook(ctx context.Context, bookID string) (entity.Bo
 	return book, file, nil
 }
 
+// DownloadBookFormat returns the file of the book with given document hash,
+// returned book has this file as the main one.
+func (uc *BookShelf) DownloadBookFormat(ctx context.Context, bookID, documentID string) (entity.Book, *os.File, error) {
+	book, err := uc.ViewBook(ctx, bookID)
+	if err != nil {
+		return book, nil, fmt.Errorf("BookShelf - DownloadBookFormat - ViewBook: %w", err)
+	}
+	for _, file := range book.Files() {
+		if file.DocumentID != documentID {
+			continue
+		}
+		book = book.WithFile(file)
+		stored, err := uc.storage.Read(ctx, book.FilePath)
+		if err != nil {
+			return book, nil, fmt.Errorf("BookShelf - DownloadBookFormat - s.storage.Read: %w", err)
+		}
+		return book, stored, nil
+	}
+	return book, nil, fmt.Errorf("BookShelf - DownloadBookFormat - %w", storage.ErrNotFound)
+}
+
+// AddBookFormat stores another file of the book, e.g. PDF of the book stored as EPUB.
+// File has its own document hash and progress, stats of all files belong to the book.
+func (uc *BookShelf) AddBookFormat(ctx context.Context, bookID string, tempFile *os.File, uploadedFilename string) (entity.Book, error) {
+	book, err := uc.ViewBook(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - ViewBook: %w", err)
+	}
+
+	koreaderPartialMD5, err := utils.PartialMD5(tempFile.Name())
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - PartialMD5: %w", err)
+	}
+	foundBook, err := uc.repo.GetByFileHash(ctx, koreaderPartialMD5)
+	if err == nil {
+		return foundBook, entity.ErrBookAlreadyExists
+	}
+
+	m, err := metadata.ExtractBookMetadata(tempFile, uploadedFilename)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - exractMetadata: %w", err)
+	}
+	if m.Format == "" {
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - %w", entity.ErrUnsupportedFormat)
+	}
+
+	createDate := time.Now()
+	storagepath := fmt.Sprintf("%s/%s.%s", createDate.Format("2006/01/02"), uuidv7.Generate(), entity.FileExtension(m.Format))
+	err = uc.storage.Write(ctx, tempFile.Name(), storagepath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - s.storage.Write: %w", err)
+	}
+
+	format := entity.BookFormat{
+		BookID:     book.ID,
+		DocumentID: koreaderPartialMD5,
+		FilePath:   storagepath,
+		Format:     m.Format,
+		Pages:      m.Pages,
+		CreatedAt:  createDate,
+	}
+	err = uc.repo.StoreFormat(ctx, format)
+	if err != nil {
+		uc.deleteFiles(ctx, []string{storagepath}, "AddBookFormat")
+		return entity.Book{}, fmt.Errorf("BookShelf - AddBookFormat - s.repo.StoreFormat: %w", err)
+	}
+	uc.logger.Info("BookShelf - AddBookFormat - documentID: %s", koreaderPartialMD5)
+
+	book.Formats = append(book.Formats, format)
+	return book, nil
+}
+
+// DeleteBookFormat removes additional file of the book, its document hash
+// stays as alias, so progress and stats of the file still belong to the book.
+func (uc *BookShelf) DeleteBookFormat(ctx context.Context, bookID, documentID string) error {
+	book, err := uc.ViewBook(ctx, bookID)
+	if err != nil {
+		return fmt.Errorf("BookShelf - DeleteBookFormat - ViewBook: %w", err)
+	}
+	for _, format := range book.Formats {
+		if format.DocumentID != documentID {
+			continue
+		}
+		err = uc.repo.DeleteFormat(ctx, book.ID, documentID)
+		if err != nil {
+			return fmt.Errorf("BookShelf - DeleteBookFormat - s.repo.DeleteFormat: %w", err)
+		}
+		uc.deleteFiles(ctx, []string{format.FilePath}, "DeleteBookFormat")
+		return nil
+	}
+	return fmt.Errorf("BookShelf - DeleteBookFormat - %w", storage.ErrNotFound)
+}
+
+// ViewCover returns cover of the book, books without cover get generated one.
 func (uc *BookShelf) ViewCover(ctx context.Context, bookID string) (*os.File, error) {
 	book, err := uc.repo.GetById(ctx, bookID)
 	if err != nil {
 		return nil, fmt.Errorf("BookShelf - ViewCover - s.repo.Get: %s", err)
 	}
-	if book.CoverPath == "" {
-		return nil, fmt.Errorf("BookShelf - ViewCover - no cover")
+	file, err := uc.readCover(ctx, book)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ViewCover - readCover: %w", err)
+	}
+	return file, nil
+}
+
+// ViewCoverThumbnail returns cover scaled down to width,
+// thumbnail is generated on first request and cached in storage.
+func (uc *BookShelf) ViewCoverThumbnail(ctx context.Context, bookID string, width int) (*os.File, error) {
+	book, err := uc.repo.GetById(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ViewCoverThumbnail - s.repo.Get: %w", err)
+	}
+
+	thumbPath := thumbnailPath(bookCoverPath(book), width)
+	file, err := uc.storage.Read(ctx, thumbPath)
+	if err == nil {
+		return file, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("BookShelf - ViewCoverThumbnail - s.storage.Read: %w", err)
+	}
+
+	cover, err := uc.readCover(ctx, book)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ViewCoverThumbnail - readCover: %w", err)
+	}
+	data, err := os.ReadFile(cover.Name())
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ViewCoverThumbnail - os.ReadFile: %w", err)
+	}
+	thumbnail, err := resizeCover(data, width)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ViewCoverThumbnail - resizeCover: %w", err)
+	}
+	return uc.cacheCover(ctx, thumbnail, thumbPath)
+}
+
+// readCover reads stored cover, placeholder is generated on first request.
+func (uc *BookShelf) readCover(ctx context.Context, book entity.Book) (*os.File, error) {
+	coverPath := bookCoverPath(book)
+	file, err := uc.storage.Read(ctx, coverPath)
+	if err == nil || book.CoverPath != "" || !errors.Is(err, storage.ErrNotFound) {
+		return file, err
+	}
+
+	cover, err := placeholder.Generate(book.Title, book.Author, book.ID)
+	if err != nil {
+		return nil, fmt.Errorf("placeholder.Generate: %w", err)
+	}
+	return uc.cacheCover(ctx, cover, coverPath)
+}
+
+// cacheCover writes generated image to storage and returns it as a file,
+// storage errors are only logged, because image may be generated again.
+func (uc *BookShelf) cacheCover(ctx context.Context, image []byte, path string) (*os.File, error) {
+	file, err := writeTempFile(image)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - cacheCover - writeTempFile: %w", err)
 	}
-	file, err := uc.storage.Read(ctx, book.CoverPath)
+	// concurrent request may have cached the same image already
+	err = uc.storage.Write(ctx, file.Name(), path)
 	if err != nil {
-		return nil, fmt.Errorf("BookShelf - ViewCover - s.storage.Read: %s", err)
+		uc.logger.Error("BookShelf - cacheCover - s.storage.Write: %s", err)
 	}
 	return file, nil
 }
 
+// ReplaceBookFile swaps the stored file of the book with a new edition.
+// Metadata is kept, previous document hash stays as alias,
+// so progress and stats from the old file still belong to the book.
+func (uc *BookShelf) ReplaceBookFile(ctx context.Context, bookID string, tempFile *os.File, uploadedFilename string) (entity.Book, error) {
+	book, err := uc.ViewBook(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - ViewBook: %w", err)
+	}
+
+	koreaderPartialMD5, err := utils.PartialMD5(tempFile.Name())
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - PartialMD5: %w", err)
+	}
+	if koreaderPartialMD5 == book.DocumentID {
+		return book, nil
+	}
+	foundBook, err := uc.repo.GetByFileHash(ctx, koreaderPartialMD5)
+	if err == nil && foundBook.ID != book.ID {
+		return foundBook, entity.ErrBookAlreadyExists
+	}
+	// main file and additional format must not share a document hash
+	for _, format := range book.Formats {
+		if format.DocumentID == koreaderPartialMD5 {
+			return book, entity.ErrBookAlreadyExists
+		}
+	}
+
+	m, err := metadata.ExtractBookMetadata(tempFile, uploadedFilename)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - exractMetadata: %w", err)
+	}
+	if m.Format == "" {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - %w", entity.ErrUnsupportedFormat)
+	}
+
+	// new file gets its own path, so previous one is never overwritten
+	updateDate := time.Now()
+	storagepath := fmt.Sprintf("%s/%s.%s", updateDate.Format("2006/01/02"), uuidv7.Generate(), entity.FileExtension(m.Format))
+	err = uc.storage.Write(ctx, tempFile.Name(), storagepath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - s.storage.Write: %w", err)
+	}
+
+	previousHash, previousPath := book.DocumentID, book.FilePath
+	book.DocumentID = koreaderPartialMD5
+	book.FilePath = storagepath
+	book.Format = m.Format
+	book.Pages = m.Pages
+	book.UpdatedAt = updateDate
+	err = uc.repo.ReplaceFile(ctx, book, previousHash)
+	if err != nil {
+		uc.deleteFiles(ctx, []string{storagepath}, "ReplaceBookFile")
+		return entity.Book{}, fmt.Errorf("BookShelf - ReplaceBookFile - s.repo.ReplaceFile: %w", err)
+	}
+	uc.logger.Info("BookShelf - ReplaceBookFile - documentID: %s -> %s", previousHash, koreaderPartialMD5)
+
+	err = uc.storage.Delete(ctx, previousPath)
+	if err != nil {
+		uc.logger.Error("BookShelf - ReplaceBookFile - s.storage.Delete: %s", err)
+	}
+	return book, nil
+}
+
+// DeleteBook moves book to trash, files are kept until purge.
+func (uc *BookShelf) DeleteBook(ctx context.Context, bookID string) error {
+	err := uc.repo.Delete(ctx, bookID)
+	if err != nil {
+		return fmt.Errorf("BookShelf - DeleteBook - s.repo.Delete: %w", err)
+	}
+	return nil
+}
+
+func (uc *BookShelf) RestoreBook(ctx context.Context, bookID string) error {
+	err := uc.repo.Restore(ctx, bookID)
+	if err != nil {
+		return fmt.Errorf("BookShelf - RestoreBook - s.repo.Restore: %w", err)
+	}
+	return nil
+}
+
+func (uc *BookShelf) ListTrash(ctx context.Context) ([]entity.Book, error) {
+	books, err := uc.repo.ListDeleted(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - ListTrash - s.repo.ListDeleted: %w", err)
+	}
+	return books, nil
+}
+
+// PurgeTrash removes files and records of books,
+// which are in trash longer than retention.
+func (uc *BookShelf) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	books, err := uc.repo.ListDeleted(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("BookShelf - PurgeTrash - s.repo.ListDeleted: %w", err)
+	}
+
+	purged := 0
+	deadline := time.Now().Add(-retention)
+	for _, book := range books {
+		if book.DeletedAt.After(deadline) {
+			continue
+		}
+		formats, err := uc.repo.ListFormats(ctx, []string{book.ID})
+		if err != nil {
+			return purged, fmt.Errorf("BookShelf - PurgeTrash - s.repo.ListFormats: %w", err)
+		}
+		paths := append([]string{book.FilePath}, coverFiles(bookCoverPath(book))...)
+		for _, format := range formats {
+			paths = append(paths, format.FilePath)
+		}
+		// rows go first, so failed purge does not leave books without files
+		err = uc.repo.Purge(ctx, book.ID)
+		if err != nil {
+			return purged, fmt.Errorf("BookShelf - PurgeTrash - s.repo.Purge: %w", err)
+		}
+		uc.deleteFiles(ctx, paths, "PurgeTrash")
+		purged++
+	}
+	return purged, nil
+}
+
+// FindDuplicates lists groups of books, which are probably the same book.
+func (uc *BookShelf) FindDuplicates(ctx context.Context) ([]DuplicateGroup, error) {
+	books, err := uc.repo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("BookShelf - FindDuplicates - s.repo.ListAll: %w", err)
+	}
+	return findDuplicates(books), nil
+}
+
+// MergeBooks merges duplicates into the book: their files become
+// additional formats, progress, stats and collections follow them.
+// Metadata of the book is kept, missing fields are taken from duplicates.
+func (uc *BookShelf) MergeBooks(ctx context.Context, bookID string, duplicateIDs []string) (entity.Book, error) {
+	book, err := uc.repo.GetById(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - MergeBooks - s.repo.Get: %w", err)
+	}
+
+	merged := book
+	duplicates := make([]entity.Book, 0, len(duplicateIDs))
+	for _, duplicateID := range duplicateIDs {
+		if duplicateID == book.ID {
+			continue
+		}
+		duplicate, err := uc.repo.GetById(ctx, duplicateID)
+		if err != nil {
+			return entity.Book{}, fmt.Errorf("BookShelf - MergeBooks - s.repo.Get: %w", err)
+		}
+		duplicates = append(duplicates, duplicate)
+		merged = mergeMetadata(merged, duplicate)
+		merged.CoverPath = utils.If(merged.CoverPath == "", duplicate.CoverPath, merged.CoverPath)
+	}
+	merged.UpdatedAt = time.Now()
+	err = uc.repo.Merge(ctx, merged, duplicates)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - MergeBooks - s.repo.Merge: %w", err)
+	}
+
+	// files are removed only after duplicates are gone from database
+	for _, duplicate := range duplicates {
+		uc.logger.Info("BookShelf - MergeBooks - %s -> %s", duplicate.ID, book.ID)
+		if duplicate.CoverPath == "" || duplicate.CoverPath != merged.CoverPath {
+			uc.deleteFiles(ctx, coverFiles(bookCoverPath(duplicate)), "MergeBooks")
+		}
+	}
+	if merged.CoverPath != book.CoverPath || merged.Title != book.Title || merged.Author != book.Author {
+		uc.deleteFiles(ctx, coverFiles(placeholderPath(book)), "MergeBooks")
+	}
+	return merged, nil
+}
+
+// mergeMetadata fills empty fields of the book from duplicate.
+func mergeMetadata(book, duplicate entity.Book) entity.Book {
+	book.Author = utils.If(book.Author == "", duplicate.Author, book.Author)
+	book.Publisher = utils.If(book.Publisher == "", duplicate.Publisher, book.Publisher)
+	book.Year = utils.If(book.Year == 0, duplicate.Year, book.Year)
+	book.ISBN = utils.If(book.ISBN == "", duplicate.ISBN, book.ISBN)
+	book.Series = utils.If(book.Series == "", duplicate.Series, book.Series)
+	book.SeriesIndex = utils.If(book.SeriesIndex == 0, duplicate.SeriesIndex, book.SeriesIndex)
+	book.Subjects = utils.If(len(book.Subjects) == 0, duplicate.Subjects, book.Subjects)
+	book.Language = utils.If(book.Language == "", duplicate.Language, book.Language)
+	book.Description = utils.If(book.Description == "", duplicate.Description, book.Description)
+	return book
+}
+
+// deleteFiles removes files, which are not needed anymore, errors are only logged.
+func (uc *BookShelf) deleteFiles(ctx context.Context, paths []string, method string) {
+	for _, path := range paths {
+		err := uc.storage.Delete(ctx, path)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			uc.logger.Error("BookShelf - %s - s.storage.Delete: %s", method, err)
+		}
+	}
+}
+
+// UpdateCover stores new cover image of the book and removes previous one
+// with its thumbnails. JPEG, PNG and WebP images are accepted and stored as JPEG.
+func (uc *BookShelf) UpdateCover(ctx context.Context, bookID string, cover []byte) (entity.Book, error) {
+	book, err := uc.repo.GetById(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - UpdateCover - s.repo.Get: %w", err)
+	}
+	cover, err = normalizeCover(cover)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - UpdateCover - normalizeCover: %w", err)
+	}
+
+	// every cover gets new path, because storage may not overwrite files
+	coverPath, err := writeCover(ctx, uc.storage, cover, fmt.Sprintf("%s-%d", book.ID, time.Now().UnixNano()))
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - UpdateCover - writeCover: %w", err)
+	}
+	err = uc.repo.UpdateCover(ctx, book.ID, coverPath)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - UpdateCover - s.repo.UpdateCover: %w", err)
+	}
+
+	if book.CoverPath != coverPath {
+		for _, path := range coverFiles(bookCoverPath(book)) {
+			err = uc.storage.Delete(ctx, path)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				uc.logger.Error("BookShelf - UpdateCover - s.storage.Delete: %s", err)
+			}
+		}
+	}
+	book.CoverPath = coverPath
+	return book, nil
+}
+
+// ReextractCover replaces cover with the one embedded in the book file.
+func (uc *BookShelf) ReextractCover(ctx context.Context, bookID string) (entity.Book, error) {
+	book, file, err := uc.DownloadBook(ctx, bookID)
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReextractCover - DownloadBook: %w", err)
+	}
+	// storage returns closed file
+	bookFile, err := os.Open(file.Name())
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReextractCover - os.Open: %w", err)
+	}
+	defer bookFile.Close()
+
+	m, err := metadata.ExtractBookMetadata(bookFile, book.Filename())
+	if err != nil {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReextractCover - exractMetadata: %w", err)
+	}
+	if len(m.Cover) == 0 {
+		return entity.Book{}, fmt.Errorf("BookShelf - ReextractCover - %w", entity.ErrCoverNotFound)
+	}
+	return uc.UpdateCover(ctx, book.ID, m.Cover)
+}
+
 func writeCover(
 	ctx context.Context,
 	storage storage.Storage,
 	cover []byte,
-	bookID string,
+	name string,
 ) (string, error) {
 	if len(cover) == 0 {
 		return "", nil
 	}
-	coverTempFile, err := os.CreateTemp("", "cover")
-	if err != nil {
-		return "", fmt.Errorf("BookShelf - writeCover - os.CreateTemp: %w", err)
-	}
-	defer coverTempFile.Close()
-	_, err = coverTempFile.Write(cover)
+	coverTempFile, err := writeTempFile(cover)
 	if err != nil {
-		return "", fmt.Errorf("BookShelf - writeCover - coverTempFile.Write: %w", err)
+		return "", fmt.Errorf("BookShelf - writeCover - writeTempFile: %w", err)
 	}
 
-	coverpath := fmt.Sprintf("covers/%s.jpg", bookID)
+	coverpath := fmt.Sprintf("covers/%s.jpg", name)
 	err = storage.Write(ctx, coverTempFile.Name(), coverpath)
 	if err != nil {
 		return "", fmt.Errorf("BookShelf - writeCover - s.storage.Write: %w", err)
 	}
 	return coverpath, nil
 }
+
+// titleFromFilename is used for books without title in metadata,
+// e.g. comics without ComicInfo.xml.
+func titleFromFilename(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSpace(strings.TrimSuffix(base, filepath.Ext(base)))
+}
@@ -0,0 +1,30 @@
+package library
+
+import (
+	"context"
+	"time"
+
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// RunTrashPurge periodically purges books, which are in trash
+// longer than retention. It blocks until context is done.
+func RunTrashPurge(ctx context.Context, shelf Shelf, retention, interval time.Duration, l logger.Interface) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := shelf.PurgeTrash(ctx, retention)
+		if err != nil {
+			l.Error("library - RunTrashPurge - shelf.PurgeTrash: %s", err)
+		} else if purged > 0 {
+			l.Info("library - RunTrashPurge - purged books: %d", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
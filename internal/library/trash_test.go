@@ -0,0 +1,68 @@
+package library_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/pkg/logger"
+)
+
+// trashRepo keeps deleted books, purge fails when purgeErr is set.
+type trashRepo struct {
+	library.BookRepo
+	deleted  []entity.Book
+	purgeErr error
+}
+
+func (r *trashRepo) ListDeleted(ctx context.Context) ([]entity.Book, error) {
+	return r.deleted, nil
+}
+
+func (r *trashRepo) ListFormats(ctx context.Context, ids []string) ([]entity.BookFormat, error) {
+	return []entity.BookFormat{{BookID: "1", FilePath: "1.pdf", Format: "pdf"}}, nil
+}
+
+func (r *trashRepo) Purge(ctx context.Context, id string) error {
+	if r.purgeErr != nil {
+		return r.purgeErr
+	}
+	r.deleted = r.deleted[:0]
+	return nil
+}
+
+func TestShelfPurgeTrash(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	file := filepath.Join(t.TempDir(), "book")
+	require.NoError(t, os.WriteFile(file, []byte("book"), 0o644))
+	// the main file is already gone, e.g. after failed purge
+	require.NoError(t, store.Write(ctx, file, "1.pdf"))
+
+	repo := &trashRepo{
+		deleted:  []entity.Book{{ID: "1", FilePath: "1.epub", DeletedAt: time.Now().Add(-time.Hour)}},
+		purgeErr: assert.AnError,
+	}
+	shelf := library.NewBookShelf(store, repo, logger.New("error"))
+
+	// files stay, while book is in database
+	_, err := shelf.PurgeTrash(ctx, time.Minute)
+	assert.ErrorIs(t, err, assert.AnError)
+	_, err = store.Read(ctx, "1.pdf")
+	require.NoError(t, err)
+
+	repo.purgeErr = nil
+	purged, err := shelf.PurgeTrash(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = store.Read(ctx, "1.pdf")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -47,18 +47,25 @@ type BookStats struct {
 }
 
 func (s *KOReaderPGStats) GetBookStats(ctx context.Context, fileHash string) (*BookStats, error) {
+	// page numbers differ between formats, so pages are counted per file,
+	// while time and days are summed over all formats of the book
 	query := `
-		WITH daily_reads AS (
-			SELECT DISTINCT DATE(start_time) as read_date
+		WITH file_reads AS (
+			SELECT
+				COUNT(DISTINCT page) as read_pages,
+				SUM(duration) as read_time
 			FROM stats_page_stat_data
-			WHERE koreader_partial_md5 = $1
+			WHERE koreader_partial_md5 IN (SELECT library_book_all_hashes($1))
+			GROUP BY koreader_partial_md5
 		)
-		SELECT 
-			COUNT(DISTINCT page) as total_read_pages,
-			SUM(duration) as total_read_time,
-			COUNT(DISTINCT DATE(start_time)) as total_read_days
-		FROM stats_page_stat_data
-		WHERE koreader_partial_md5 = $1
+		SELECT
+			COALESCE((SELECT SUM(read_pages) FROM file_reads), 0)::int as total_read_pages,
+			COALESCE((SELECT SUM(read_time) FROM file_reads), 0)::int as total_read_time,
+			(
+				SELECT COUNT(DISTINCT DATE(start_time))
+				FROM stats_page_stat_data
+				WHERE koreader_partial_md5 IN (SELECT library_book_all_hashes($1))
+			) as total_read_days
 	`
 
 	var stats BookStats
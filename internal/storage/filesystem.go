@@ -75,6 +75,14 @@ func (s *FilesystemStorage) Write(ctx context.Context, src, dest string) error {
 	return nil
 }
 
+func (s *FilesystemStorage) Delete(ctx context.Context, p string) error {
+	err := os.Remove(path.Join(s.root, p))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
 func checkSystemWrites(root string) error {
 	// Create a temporary file in the root directory
 	tempFile, err := os.CreateTemp(root, "write_test")
@@ -50,4 +50,12 @@ func TestFilesystemStorage(t *testing.T) {
 	if string(readBody) != string(body) {
 		t.Errorf("Expected body %s, got %s", string(body), string(readBody))
 	}
+	err = st.Delete(ctx, "test")
+	if err != nil {
+		t.Errorf("Error deleting file: %v", err)
+	}
+	_, err = st.Read(ctx, "test")
+	if err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
 }
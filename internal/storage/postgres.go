@@ -2,9 +2,12 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/vanadium23/kompanion/pkg/postgres"
 	"github.com/vanadium23/kompanion/pkg/utils"
 )
@@ -56,6 +59,9 @@ func (ps *PostgresStorage) Read(ctx context.Context, filepath string) (*os.File,
 
 	var data []byte
 	err := ps.Pool.QueryRow(ctx, sql, args...).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("PostgresStorage - Read - r.Pool.QueryRow: %w", err)
 	}
@@ -72,3 +78,21 @@ func (ps *PostgresStorage) Read(ctx context.Context, filepath string) (*os.File,
 	}
 	return tempFile, nil
 }
+
+func (ps *PostgresStorage) Delete(ctx context.Context, filepath string) error {
+	sql := `
+		DELETE FROM storage_blob
+		WHERE file_path = $1
+	`
+	args := []interface{}{filepath}
+
+	tag, err := ps.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("PostgresStorage - Delete - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
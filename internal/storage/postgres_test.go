@@ -77,7 +77,7 @@ func TestPostgresStorage(t *testing.T) {
 			WillReturnError(pgx.ErrNoRows)
 
 		_, err = store.Read(context.Background(), "non-existent.txt")
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
 
 		err = mock.ExpectationsWereMet()
 		require.NoError(t, err)
@@ -95,6 +95,30 @@ func TestPostgresStorage(t *testing.T) {
 		err = store.Write(context.Background(), "non-existent.txt", "test.txt")
 		assert.Error(t, err)
 
+		err = mock.ExpectationsWereMet()
+		require.NoError(t, err)
+	})
+	t.Run("delete file", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		pg := postgres.Mock(mock)
+		store := storage.NewPostgresStorage(pg)
+
+		mock.ExpectExec("DELETE FROM storage_blob").
+			WithArgs("test.txt").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectExec("DELETE FROM storage_blob").
+			WithArgs("test.txt").
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		err = store.Delete(context.Background(), "test.txt")
+		require.NoError(t, err)
+
+		err = store.Delete(context.Background(), "test.txt")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
 		err = mock.ExpectationsWereMet()
 		require.NoError(t, err)
 	})
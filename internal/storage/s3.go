@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3Timeout       = 10 * time.Minute
+	// only the start of error response is kept in error
+	s3ErrorLimit = 1024
+	// SHA-256 of empty body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Config describes bucket of S3-compatible storage, e.g. AWS S3 or MinIO.
+type S3Config struct {
+	Bucket    string
+	Endpoint  string // e.g. http://minio:9000, AWS endpoint of the region by default
+	Region    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool   // bucket in path instead of host name, as MinIO expects
+	Prefix    string // prefix of object keys, e.g. books/
+}
+
+// S3Storage keeps files as objects of S3-compatible bucket.
+// Requests are signed with AWS Signature Version 4,
+// anonymous requests are sent without credentials.
+type S3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Storage(cfg S3Config, client *http.Client) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is empty")
+	}
+	if cfg.Region == "" {
+		cfg.Region = s3DefaultRegion
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3 endpoint is not a valid url: %s", cfg.Endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: s3Timeout}
+	}
+	return &S3Storage{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+func (s *S3Storage) Write(ctx context.Context, source string, filepath string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.request(ctx, http.MethodPut, filepath, file, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return fmt.Errorf("S3Storage - Write - s.request: %w", err)
+	}
+	req.ContentLength = size
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3Storage - Write - s.client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("S3Storage - Write - %w", responseError(resp))
+	}
+	return nil
+}
+
+func (s *S3Storage) Read(ctx context.Context, filepath string) (*os.File, error) {
+	req, err := s.request(ctx, http.MethodGet, filepath, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("S3Storage - Read - s.request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3Storage - Read - s.client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("S3Storage - Read - %w", responseError(resp))
+	}
+
+	// make by temp files
+	tempFile, err := os.CreateTemp("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer tempFile.Close()
+	_, err = io.Copy(tempFile, resp.Body)
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return nil, fmt.Errorf("S3Storage - Read - io.Copy: %w", err)
+	}
+	return tempFile, nil
+}
+
+// Delete removes the object, S3 does not tell about missing objects on delete,
+// so existence is checked first.
+func (s *S3Storage) Delete(ctx context.Context, filepath string) error {
+	req, err := s.request(ctx, http.MethodHead, filepath, nil, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("S3Storage - Delete - s.request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3Storage - Delete - s.client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("S3Storage - Delete - %w", responseError(resp))
+	}
+
+	req, err = s.request(ctx, http.MethodDelete, filepath, nil, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("S3Storage - Delete - s.request: %w", err)
+	}
+	deleted, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3Storage - Delete - s.client.Do: %w", err)
+	}
+	defer deleted.Body.Close()
+	if deleted.StatusCode != http.StatusNoContent && deleted.StatusCode != http.StatusOK {
+		return fmt.Errorf("S3Storage - Delete - %w", responseError(deleted))
+	}
+	return nil
+}
+
+// request builds signed request to the object.
+func (s *S3Storage) request(ctx context.Context, method, filepath string, body io.Reader, payloadHash string) (*http.Request, error) {
+	host := s.endpoint.Host
+	key := uriEncode(s.cfg.Prefix + strings.TrimPrefix(filepath, "/"))
+	path := s.endpoint.Path + "/" + key
+	if s.cfg.PathStyle {
+		path = s.endpoint.Path + "/" + uriEncode(s.cfg.Bucket) + "/" + key
+	} else {
+		host = s.cfg.Bucket + "." + host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.AccessKey != "" {
+		s.sign(req, path, payloadHash, time.Now().UTC())
+	}
+	return req, nil
+}
+
+// sign adds Authorization header of AWS Signature Version 4.
+func (s *S3Storage) sign(req *http.Request, path, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // no query
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode escapes object key as S3 expects, slashes are kept.
+func uriEncode(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorLimit))
+	return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,183 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/internal/storage"
+)
+
+const (
+	s3AccessKey = "minioadmin"
+	s3SecretKey = "minio-secret"
+	s3Region    = "eu-central-1"
+)
+
+// fakeS3 is a stand-in of S3-compatible server: objects are kept in memory,
+// requests are checked with AWS Signature Version 4.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte // bucket/key
+	hosts   []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if bucket, _, ok := strings.Cut(r.Host, ".s3.test"); ok {
+		name = bucket + "/" + name
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts = append(f.hosts, r.Host)
+	data, ok := f.objects[name]
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[name] = body
+	case http.MethodGet, http.MethodHead:
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) authorized(r *http.Request) bool {
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 {
+		return false
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if r.Method == http.MethodPut {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != payloadHash {
+			return false
+		}
+	}
+
+	canonical := strings.Join([]string{
+		r.Method, r.URL.EscapedPath(), "",
+		"host:" + r.Host, "x-amz-content-sha256:" + payloadHash, "x-amz-date:" + amzDate, "",
+		"host;x-amz-content-sha256;x-amz-date", payloadHash,
+	}, "\n")
+	scope := amzDate[:8] + "/" + s3Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	key := []byte("AWS4" + s3SecretKey)
+	for _, part := range []string{amzDate[:8], s3Region, "s3", "aws4_request"} {
+		key = sign(key, part)
+	}
+	signature := hex.EncodeToString(sign(key, "AWS4-HMAC-SHA256\n"+amzDate+"\n"+scope+"\n"+hex.EncodeToString(canonicalHash[:])))
+
+	expected := "AWS4-HMAC-SHA256 Credential=" + s3AccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + signature
+	return r.Header.Get("Authorization") == expected
+}
+
+func sign(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func writeTempFile(t *testing.T, body string) string {
+	tempFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+	_, err = tempFile.WriteString(body)
+	require.NoError(t, err)
+	require.NoError(t, tempFile.Close())
+	return tempFile.Name()
+}
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+	s3 := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	st, err := storage.NewS3Storage(storage.S3Config{
+		Bucket:    "books",
+		Endpoint:  server.URL,
+		Region:    s3Region,
+		AccessKey: s3AccessKey,
+		SecretKey: s3SecretKey,
+		PathStyle: true,
+		Prefix:    "kompanion/",
+	}, nil)
+	require.NoError(t, err)
+
+	path := "2026/10/18/War & Peace.epub"
+	require.NoError(t, st.Write(ctx, writeTempFile(t, "Hello, World!"), path))
+	assert.Contains(t, s3.objects, "books/kompanion/"+path)
+
+	readFile, err := st.Read(ctx, path)
+	require.NoError(t, err)
+	readBody, err := os.ReadFile(readFile.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!", string(readBody))
+
+	require.NoError(t, st.Delete(ctx, path))
+	_, err = st.Read(ctx, path)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, st.Delete(ctx, path), storage.ErrNotFound)
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		st, err := storage.NewS3Storage(storage.S3Config{
+			Bucket:    "books",
+			Endpoint:  server.URL,
+			Region:    s3Region,
+			AccessKey: s3AccessKey,
+			SecretKey: "wrong",
+			PathStyle: true,
+		}, nil)
+		require.NoError(t, err)
+
+		err = st.Write(ctx, writeTempFile(t, "Hello"), "book.epub")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+	})
+
+	t.Run("virtual-hosted style", func(t *testing.T) {
+		// bucket host names are resolved to the stand-in
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		}}
+		st, err := storage.NewS3Storage(storage.S3Config{
+			Bucket:    "books",
+			Endpoint:  "http://s3.test",
+			Region:    s3Region,
+			AccessKey: s3AccessKey,
+			SecretKey: s3SecretKey,
+		}, client)
+		require.NoError(t, err)
+
+		require.NoError(t, st.Write(ctx, writeTempFile(t, "Hello"), "book.epub"))
+		assert.Contains(t, s3.objects, "books/book.epub")
+		assert.Equal(t, "books.s3.test", s3.hosts[len(s3.hosts)-1])
+	})
+}
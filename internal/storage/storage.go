@@ -2,11 +2,12 @@ package storage
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/vanadium23/kompanion/pkg/postgres"
 )
 
-func NewStorage(storage_type, dir string, pg *postgres.Postgres) (Storage, error) {
+func NewStorage(storage_type, dir string, s3 S3Config, pg *postgres.Postgres) (Storage, error) {
 	switch storage_type {
 	case "memory":
 		return NewMemoryStorage(), nil
@@ -16,6 +17,13 @@ func NewStorage(storage_type, dir string, pg *postgres.Postgres) (Storage, error
 	case "postgres":
 		st := NewPostgresStorage(pg)
 		return st, nil
+	case "s3":
+		// path is a prefix of object keys in bucket
+		if dir != "" {
+			s3.Prefix = strings.TrimSuffix(strings.TrimPrefix(dir, "/"), "/") + "/"
+		}
+		st, err := NewS3Storage(s3, nil)
+		return st, err
 	}
 	return nil, errors.New("unknown storage type")
 }
@@ -50,6 +50,21 @@ func (mr *MockProgressRepoMockRecorder) GetBookHistory(ctx, bookID, limit interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookHistory", reflect.TypeOf((*MockProgressRepo)(nil).GetBookHistory), ctx, bookID, limit)
 }
 
+// GetLastProgress mocks base method.
+func (m *MockProgressRepo) GetLastProgress(ctx context.Context, documentIDs []string) ([]entity.Progress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastProgress", ctx, documentIDs)
+	ret0, _ := ret[0].([]entity.Progress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastProgress indicates an expected call of GetLastProgress.
+func (mr *MockProgressRepoMockRecorder) GetLastProgress(ctx, documentIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastProgress", reflect.TypeOf((*MockProgressRepo)(nil).GetLastProgress), ctx, documentIDs)
+}
+
 // Store mocks base method.
 func (m *MockProgressRepo) Store(ctx context.Context, t entity.Progress) error {
 	m.ctrl.T.Helper()
@@ -102,6 +117,21 @@ func (mr *MockProgressMockRecorder) Fetch(ctx, bookID interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockProgress)(nil).Fetch), ctx, bookID)
 }
 
+// FetchMany mocks base method.
+func (m *MockProgress) FetchMany(ctx context.Context, documentIDs []string) (map[string]entity.Progress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchMany", ctx, documentIDs)
+	ret0, _ := ret[0].(map[string]entity.Progress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchMany indicates an expected call of FetchMany.
+func (mr *MockProgressMockRecorder) FetchMany(ctx, documentIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchMany", reflect.TypeOf((*MockProgress)(nil).FetchMany), ctx, documentIDs)
+}
+
 // Sync mocks base method.
 func (m *MockProgress) Sync(arg0 context.Context, arg1 entity.Progress) (entity.Progress, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +49,25 @@ func (uc *ProgressSyncUseCase) Fetch(ctx context.Context, bookID string) (entity
 	last := doc[0]
 	// rewrite koreader device with our authed device
 	last.Device = last.AuthDeviceName
+	// progress may be stored under previous hash of the replaced file
+	last.Document = bookID
 
 	return last, nil
 }
+
+// FetchMany returns last progress of documents with a single query,
+// documents without progress are omitted.
+func (uc *ProgressSyncUseCase) FetchMany(ctx context.Context, documentIDs []string) (map[string]entity.Progress, error) {
+	docs, err := uc.repo.GetLastProgress(ctx, documentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ProgressSyncUseCase - FetchMany - s.repo.GetLastProgress: %w", err)
+	}
+
+	progress := make(map[string]entity.Progress, len(docs))
+	for _, last := range docs {
+		// rewrite koreader device with our authed device
+		last.Device = last.AuthDeviceName
+		progress[last.Document] = last
+	}
+	return progress, nil
+}
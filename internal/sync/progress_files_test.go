@@ -0,0 +1,244 @@
+package sync_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/moroz/uuidv7-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion"
+	"github.com/vanadium23/kompanion/internal/entity"
+	"github.com/vanadium23/kompanion/internal/library"
+	"github.com/vanadium23/kompanion/internal/storage"
+	"github.com/vanadium23/kompanion/internal/sync"
+	"github.com/vanadium23/kompanion/pkg/converter"
+	"github.com/vanadium23/kompanion/pkg/logger"
+	"github.com/vanadium23/kompanion/pkg/postgres"
+)
+
+// setupTestDatabase migrates database from KOMPANION_TEST_PG_URL,
+// hash resolution lives in SQL, so it is checked against real postgres.
+func setupTestDatabase(t *testing.T) *postgres.Postgres {
+	url := os.Getenv("KOMPANION_TEST_PG_URL")
+	if url == "" {
+		t.Skip("KOMPANION_TEST_PG_URL is not set")
+	}
+
+	source, err := iofs.New(kompanion.Migrations, "migrations")
+	require.NoError(t, err)
+	m, err := migrate.NewWithSourceInstance("iofs", source, url)
+	require.NoError(t, err)
+	defer m.Close()
+	err = m.Up()
+	if !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+
+	pg, err := postgres.New(url)
+	require.NoError(t, err)
+	t.Cleanup(pg.Close)
+	return pg
+}
+
+func TestProgressSyncBookFiles(t *testing.T) {
+	ctx := context.Background()
+	pg := setupTestDatabase(t)
+	books := library.NewBookDatabaseRepo(pg)
+	uc := sync.NewProgressSync(sync.NewProgressDatabaseRepo(pg))
+
+	id := uuidv7.Generate().String()
+	hash := func(name string) string { return id + "-" + name }
+	now := time.Now()
+	t.Cleanup(func() {
+		pg.Pool.Exec(ctx, `DELETE FROM library_book WHERE id = $1`, id)
+		pg.Pool.Exec(ctx, `DELETE FROM sync_progress WHERE koreader_partial_md5 LIKE $1`, id+"%")
+	})
+
+	book := entity.Book{
+		ID: id, Title: "Dune", DocumentID: hash("epub-old"), FilePath: id + "-old.epub",
+		CreatedAt: now, UpdatedAt: now, Subjects: []string{},
+	}
+	require.NoError(t, books.Store(ctx, book))
+	book.DocumentID, book.FilePath = hash("epub"), id+".epub"
+	require.NoError(t, books.ReplaceFile(ctx, book, hash("epub-old")))
+	require.NoError(t, books.StoreFormat(ctx, entity.BookFormat{
+		BookID: id, DocumentID: hash("pdf"), FilePath: id + ".pdf", Format: "pdf", CreatedAt: now,
+	}))
+
+	epub := entity.Progress{
+		Document: hash("epub"), Percentage: 0.5, Progress: "/body/DocFragment[10]",
+		Device: "kobo", DeviceID: "kobo", AuthDeviceName: "kobo", Timestamp: now.Unix(),
+	}
+	_, err := uc.Sync(ctx, epub)
+	require.NoError(t, err)
+
+	// format has its own position, xpointer of EPUB is useless for PDF
+	progress, err := uc.Fetch(ctx, hash("pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.Progress{}, progress)
+
+	// previous hash of the same file is synced
+	progress, err = uc.Fetch(ctx, hash("epub-old"))
+	require.NoError(t, err)
+	assert.Equal(t, epub.Progress, progress.Progress)
+
+	pdf := epub
+	pdf.Document, pdf.Progress, pdf.Timestamp = hash("pdf"), "42", now.Unix()+1
+	_, err = uc.Sync(ctx, pdf)
+	require.NoError(t, err)
+
+	progress, err = uc.Fetch(ctx, hash("pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "42", progress.Progress)
+	progress, err = uc.Fetch(ctx, hash("epub"))
+	require.NoError(t, err)
+	assert.Equal(t, epub.Progress, progress.Progress)
+
+	// the same positions are fetched for many documents at once
+	many, err := uc.FetchMany(ctx, []string{hash("epub-old"), hash("pdf"), hash("unknown")})
+	require.NoError(t, err)
+	require.Len(t, many, 2)
+	assert.Equal(t, epub.Progress, many[hash("epub-old")].Progress)
+	assert.Equal(t, "42", many[hash("pdf")].Progress)
+
+	// removed format keeps its own position and the book
+	require.NoError(t, books.DeleteFormat(ctx, id, hash("pdf")))
+	progress, err = uc.Fetch(ctx, hash("epub"))
+	require.NoError(t, err)
+	assert.Equal(t, epub.Progress, progress.Progress)
+	found, err := books.GetByFileHash(ctx, hash("pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, id, found.ID)
+}
+
+func TestProgressSyncMergedBooks(t *testing.T) {
+	ctx := context.Background()
+	pg := setupTestDatabase(t)
+	books := library.NewBookDatabaseRepo(pg)
+	collections := library.NewCollectionDatabaseRepo(pg)
+	uc := sync.NewProgressSync(sync.NewProgressDatabaseRepo(pg))
+
+	id, duplicateID, collectionID := uuidv7.Generate().String(), uuidv7.Generate().String(), uuidv7.Generate().String()
+	hash := func(name string) string { return id + "-" + name }
+	now := time.Now()
+	t.Cleanup(func() {
+		pg.Pool.Exec(ctx, `DELETE FROM library_book WHERE id = ANY($1::uuid[])`, []string{id, duplicateID})
+		pg.Pool.Exec(ctx, `DELETE FROM library_collection WHERE id = $1`, collectionID)
+		pg.Pool.Exec(ctx, `DELETE FROM sync_progress WHERE koreader_partial_md5 LIKE $1`, id+"%")
+	})
+
+	book := entity.Book{
+		ID: id, Title: "Dune", DocumentID: hash("epub"), FilePath: id + ".epub",
+		CreatedAt: now, UpdatedAt: now, Subjects: []string{},
+	}
+	require.NoError(t, books.Store(ctx, book))
+	duplicate := entity.Book{
+		ID: duplicateID, Title: "Dune", DocumentID: hash("pdf-old"), FilePath: id + "-old.pdf",
+		CreatedAt: now, UpdatedAt: now, Subjects: []string{},
+	}
+	require.NoError(t, books.Store(ctx, duplicate))
+	duplicate.DocumentID, duplicate.FilePath = hash("pdf"), id+".pdf"
+	require.NoError(t, books.ReplaceFile(ctx, duplicate, hash("pdf-old")))
+	require.NoError(t, books.StoreFormat(ctx, entity.BookFormat{
+		BookID: duplicateID, DocumentID: hash("txt"), FilePath: id + ".txt", Format: "txt", CreatedAt: now,
+	}))
+	require.NoError(t, collections.Store(ctx, entity.Collection{ID: collectionID, Name: id, CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, collections.AddBook(ctx, collectionID, duplicateID))
+
+	pdf := entity.Progress{
+		Document: hash("pdf"), Percentage: 0.5, Progress: "42",
+		Device: "kobo", DeviceID: "kobo", AuthDeviceName: "kobo", Timestamp: now.Unix(),
+	}
+	_, err := uc.Sync(ctx, pdf)
+	require.NoError(t, err)
+
+	require.NoError(t, books.Merge(ctx, book, []entity.Book{duplicate}))
+
+	_, err = books.GetById(ctx, duplicateID)
+	require.Error(t, err)
+	// main file and format of duplicate are formats of the book now
+	formats, err := books.ListFormats(ctx, []string{id})
+	require.NoError(t, err)
+	documentIDs := make([]string, 0, len(formats))
+	for _, format := range formats {
+		documentIDs = append(documentIDs, format.DocumentID)
+	}
+	assert.ElementsMatch(t, []string{hash("pdf"), hash("txt")}, documentIDs)
+	// previous hash of duplicate is alias of the book
+	found, err := books.GetByFileHash(ctx, hash("pdf-old"))
+	require.NoError(t, err)
+	assert.Equal(t, id, found.ID)
+
+	inCollections, err := collections.ListByBook(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, inCollections, 1)
+	assert.Equal(t, collectionID, inCollections[0].ID)
+
+	// merged file keeps its position, main file does not get it
+	progress, err := uc.Fetch(ctx, hash("pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, pdf.Progress, progress.Progress)
+	progress, err = uc.Fetch(ctx, hash("epub"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.Progress{}, progress)
+}
+
+// fakeConverter writes text version of the book, as a real converter would.
+const fakeConverter = `#!/bin/sh
+{ echo "Dune"; echo; cat "$1"; } > "$2"
+`
+
+func TestProgressSyncConvertedFile(t *testing.T) {
+	ctx := context.Background()
+	pg := setupTestDatabase(t)
+	books := library.NewBookDatabaseRepo(pg)
+	store := storage.NewMemoryStorage()
+	shelf := library.NewBookShelf(store, books, logger.New("error"))
+	uc := sync.NewProgressSync(sync.NewProgressDatabaseRepo(pg))
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "fake-convert")
+	require.NoError(t, os.WriteFile(script, []byte(fakeConverter), 0o755))
+	c, err := converter.NewCommand("txt", []string{"epub"}, script+" {input} {output}")
+	require.NoError(t, err)
+	queue := library.NewConversionQueue(shelf, []converter.Converter{c}, 1, time.Minute, logger.New("error"))
+
+	id := uuidv7.Generate().String()
+	now := time.Now()
+	file := filepath.Join(dir, "book.epub")
+	require.NoError(t, os.WriteFile(file, []byte(id+": a beginning is the time for taking the most delicate care.\n"), 0o644))
+	require.NoError(t, store.Write(ctx, file, id+".epub"))
+	book := entity.Book{
+		ID: id, Title: "Dune", DocumentID: id + "-epub", FilePath: id + ".epub",
+		CreatedAt: now, UpdatedAt: now, Subjects: []string{},
+	}
+	require.NoError(t, books.Store(ctx, book))
+	t.Cleanup(func() {
+		pg.Pool.Exec(ctx, `DELETE FROM library_book WHERE id = $1`, id)
+		pg.Pool.Exec(ctx, `DELETE FROM sync_progress WHERE koreader_partial_md5 = $1`, book.DocumentID)
+	})
+
+	_, err = uc.Sync(ctx, entity.Progress{
+		Document: book.DocumentID, Percentage: 0.5, Progress: "/body/DocFragment[10]",
+		Device: "kobo", DeviceID: "kobo", AuthDeviceName: "kobo", Timestamp: now.Unix(),
+	})
+	require.NoError(t, err)
+
+	converted, err := queue.Convert(ctx, library.ConversionJob{BookID: id, Format: "txt"})
+	require.NoError(t, err)
+	require.Len(t, converted.Formats, 1)
+
+	// reader of converted file can not apply EPUB xpointer
+	progress, err := uc.Fetch(ctx, converted.Formats[0].DocumentID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Progress{}, progress)
+}
@@ -37,7 +37,7 @@ func (r *ProgressDatabaseRepo) Store(ctx context.Context, t entity.Progress) err
 func (r *ProgressDatabaseRepo) GetBookHistory(ctx context.Context, bookID string, limit int) ([]entity.Progress, error) {
 	sql := `SELECT koreader_partial_md5, percentage, progress, koreader_device, koreader_device_id, created_at, auth_device_name
 		FROM sync_progress
-		WHERE koreader_partial_md5 = $1
+		WHERE koreader_partial_md5 IN (SELECT library_book_hashes($1))
 		ORDER BY created_at DESC
 		LIMIT $2`
 	args := []interface{}{bookID, limit}
@@ -65,3 +65,37 @@ func (r *ProgressDatabaseRepo) GetBookHistory(ctx context.Context, bookID string
 
 	return entities, nil
 }
+
+// GetLastProgress returns last progress of every document, which has one.
+// Progress may be stored under previous hash of the replaced file,
+// so document of the result is the requested one.
+func (r *ProgressDatabaseRepo) GetLastProgress(ctx context.Context, documentIDs []string) ([]entity.Progress, error) {
+	sql := `SELECT DISTINCT ON (d.document_id)
+			d.document_id, p.percentage, p.progress, p.koreader_device, p.koreader_device_id, p.created_at, p.auth_device_name
+		FROM unnest($1::text[]) AS d(document_id)
+		CROSS JOIN LATERAL library_book_hashes(d.document_id) AS h(hash)
+		JOIN sync_progress p ON p.koreader_partial_md5 = h.hash
+		ORDER BY d.document_id, p.created_at DESC`
+
+	rows, err := r.Pool.Query(ctx, sql, documentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ProgressDatabaseRepo - GetLastProgress - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	entities := make([]entity.Progress, 0, len(documentIDs))
+	for rows.Next() {
+		e := entity.Progress{}
+		timestamp := time.Time{}
+
+		err = rows.Scan(&e.Document, &e.Percentage, &e.Progress, &e.Device, &e.DeviceID, &timestamp, &e.AuthDeviceName)
+		if err != nil {
+			return nil, fmt.Errorf("ProgressDatabaseRepo - GetLastProgress - rows.Scan: %w", err)
+		}
+		e.Timestamp = timestamp.Unix()
+
+		entities = append(entities, e)
+	}
+
+	return entities, nil
+}
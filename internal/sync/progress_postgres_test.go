@@ -84,6 +84,37 @@ func TestProgressRepo_GetBookHistory(t *testing.T) {
 	}
 }
 
+func TestProgressRepo_GetLastProgress(t *testing.T) {
+	mock, pdr := setupTestProgressDatabaseRepo()
+	defer mock.Close()
+
+	documentIDs := []string{"epub", "pdf"}
+	now := time.Now()
+	rows := pgxmock.NewRows([]string{"document_id", "percentage", "progress", "koreader_device", "koreader_device_id", "created_at", "auth_device_name"}).
+		AddRow("epub", 0.5, "/body/DocFragment[10]", "koreader", "test", now, "kobo")
+
+	mock.ExpectQuery("SELECT DISTINCT ON \\(d.document_id\\)").
+		WithArgs(documentIDs).
+		WillReturnRows(rows)
+
+	progress, err := pdr.GetLastProgress(context.Background(), documentIDs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(progress) != 1 {
+		t.Fatalf("Expected 1 progress record, got %d", len(progress))
+	}
+
+	if progress[0].Document != "epub" || progress[0].Timestamp != now.Unix() {
+		t.Errorf("Unexpected progress %+v", progress[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func setupTestProgressDatabaseRepo() (pgxmock.PgxPoolIface, *sync.ProgressDatabaseRepo) {
 	// создать mock
 	mock, err := pgxmock.NewPool()
@@ -121,6 +121,26 @@ func TestProgressSync(t *testing.T) {
 	}
 }
 
+func TestProgressFetchMany(t *testing.T) {
+	progressSync, repo := mockedProgress(t)
+
+	documentIDs := []string{"epub", "pdf"}
+	repo.EXPECT().GetLastProgress(context.Background(), documentIDs).Return(
+		[]entity.Progress{{Document: "epub", Device: "koreader", AuthDeviceName: "kobo"}}, nil)
+
+	res, err := progressSync.FetchMany(context.Background(), documentIDs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]entity.Progress{
+		"epub": {Document: "epub", Device: "kobo", AuthDeviceName: "kobo"},
+	}, res)
+
+	errInternalServErr := errors.New("internal server error")
+	repo.EXPECT().GetLastProgress(context.Background(), documentIDs).Return(nil, errInternalServErr)
+
+	_, err = progressSync.FetchMany(context.Background(), documentIDs)
+	require.ErrorIs(t, err, errInternalServErr)
+}
+
 func mockedProgress(t *testing.T) (*sync.ProgressSyncUseCase, *MockProgressRepo) {
 	t.Helper()
 
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS library_book_search_trgm;
+DROP INDEX IF EXISTS library_book_search_fts;
+ALTER TABLE library_book DROP COLUMN IF EXISTS search_text;
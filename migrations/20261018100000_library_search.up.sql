@@ -0,0 +1,14 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE library_book ADD COLUMN search_text TEXT GENERATED ALWAYS AS (
+    coalesce(title, '') || ' ' ||
+    coalesce(author, '') || ' ' ||
+    coalesce(publisher, '') || ' ' ||
+    coalesce(series, '') || ' ' ||
+    coalesce(isbn, '')
+) STORED;
+
+CREATE INDEX library_book_search_fts ON library_book USING GIN (to_tsvector('simple', search_text));
+CREATE INDEX library_book_search_trgm ON library_book USING GIN (search_text gin_trgm_ops);
+
+COMMENT ON COLUMN library_book.search_text IS 'Concatenated metadata for full-text and trigram search';
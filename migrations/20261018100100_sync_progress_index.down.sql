@@ -0,0 +1 @@
+DROP INDEX IF EXISTS sync_progress_koreader_partial_md5_created_at;
@@ -0,0 +1 @@
+CREATE INDEX sync_progress_koreader_partial_md5_created_at ON sync_progress(koreader_partial_md5, created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS library_book_deleted_at;
+ALTER TABLE library_book DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+ALTER TABLE library_book ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX library_book_deleted_at ON library_book(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN library_book.deleted_at IS 'Book is in trash since this time and will be purged after retention period';
@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS library_book_hashes(TEXT);
+DROP VIEW IF EXISTS library_book_document;
+DROP TABLE IF EXISTS library_book_alias;
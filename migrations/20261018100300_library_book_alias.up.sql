@@ -0,0 +1,26 @@
+CREATE TABLE library_book_alias (
+    koreader_partial_md5 TEXT PRIMARY KEY,
+    library_book_id UUID NOT NULL REFERENCES library_book(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX library_book_alias_library_book_id ON library_book_alias(library_book_id);
+
+COMMENT ON TABLE library_book_alias IS 'Previous document hashes of books, which files were replaced';
+
+CREATE VIEW library_book_document AS
+SELECT id AS library_book_id, koreader_partial_md5 FROM library_book
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias;
+
+COMMENT ON VIEW library_book_document IS 'All document hashes of a book: current and aliases';
+
+CREATE FUNCTION library_book_hashes(hash TEXT) RETURNS SETOF TEXT AS $$
+    SELECT hash
+    UNION
+    SELECT d.koreader_partial_md5
+    FROM library_book_document d
+    JOIN library_book_document q ON q.library_book_id = d.library_book_id
+    WHERE q.koreader_partial_md5 = hash
+$$ LANGUAGE SQL STABLE;
+
+COMMENT ON FUNCTION library_book_hashes(TEXT) IS 'Resolves document hash to all hashes of the same book, used to match progress and stats';
@@ -0,0 +1,2 @@
+ALTER TABLE library_book DROP COLUMN IF EXISTS subjects;
+ALTER TABLE library_book DROP COLUMN IF EXISTS series_index;
@@ -0,0 +1,5 @@
+ALTER TABLE library_book ADD COLUMN series_index REAL;
+ALTER TABLE library_book ADD COLUMN subjects TEXT[];
+
+COMMENT ON COLUMN library_book.series_index IS 'Position of the book in series, may be fractional like 1.5';
+COMMENT ON COLUMN library_book.subjects IS 'Subjects or tags from book metadata';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS library_collection_book;
+DROP TABLE IF EXISTS library_collection;
@@ -0,0 +1,17 @@
+CREATE TABLE library_collection (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE library_collection_book (
+    library_collection_id UUID NOT NULL REFERENCES library_collection(id) ON DELETE CASCADE,
+    library_book_id UUID NOT NULL REFERENCES library_book(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (library_collection_id, library_book_id)
+);
+CREATE INDEX library_collection_book_library_book_id ON library_collection_book(library_book_id);
+
+COMMENT ON TABLE library_collection IS 'User defined shelves and tags, like "To read" or "Kids"';
+COMMENT ON TABLE library_collection_book IS 'Books of collections, a book may be in many collections';
@@ -0,0 +1,10 @@
+DROP VIEW IF EXISTS library_book_document;
+
+CREATE VIEW library_book_document AS
+SELECT id AS library_book_id, koreader_partial_md5 FROM library_book
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias;
+
+COMMENT ON VIEW library_book_document IS 'All document hashes of a book: current and aliases';
+
+DROP TABLE IF EXISTS library_book_format;
@@ -0,0 +1,20 @@
+CREATE TABLE library_book_format (
+    koreader_partial_md5 TEXT PRIMARY KEY,
+    library_book_id UUID NOT NULL REFERENCES library_book(id) ON DELETE CASCADE,
+    format TEXT NOT NULL,
+    storage_file_path TEXT NOT NULL,
+    pages INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX library_book_format_library_book_id ON library_book_format(library_book_id);
+
+COMMENT ON TABLE library_book_format IS 'Additional files of books, e.g. PDF of the book stored as EPUB';
+
+CREATE OR REPLACE VIEW library_book_document AS
+SELECT id AS library_book_id, koreader_partial_md5 FROM library_book
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_format;
+
+COMMENT ON VIEW library_book_document IS 'All document hashes of a book: current, aliases and additional formats';
@@ -0,0 +1,14 @@
+DROP FUNCTION IF EXISTS library_book_all_hashes(TEXT);
+DROP VIEW IF EXISTS library_book_file;
+
+CREATE OR REPLACE VIEW library_book_document AS
+SELECT id AS library_book_id, koreader_partial_md5 FROM library_book
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_format;
+
+COMMENT ON VIEW library_book_document IS 'All document hashes of a book: current, aliases and additional formats';
+COMMENT ON FUNCTION library_book_hashes(TEXT) IS 'Resolves document hash to all hashes of the same book, used to match progress and stats';
+
+ALTER TABLE library_book_alias DROP COLUMN detached;
@@ -0,0 +1,33 @@
+ALTER TABLE library_book_alias ADD COLUMN detached BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN library_book_alias.detached IS 'Hash of other file of the book, e.g. removed or merged format, it is not synced with the main file';
+
+-- progress is synced between hashes of the same file only,
+-- so additional formats do not get position of another format
+CREATE OR REPLACE VIEW library_book_document AS
+SELECT id AS library_book_id, koreader_partial_md5 FROM library_book
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias WHERE NOT detached;
+
+COMMENT ON VIEW library_book_document IS 'Document hashes of the main file of a book: current and aliases';
+
+CREATE VIEW library_book_file AS
+SELECT library_book_id, koreader_partial_md5 FROM library_book_document
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_alias WHERE detached
+UNION ALL
+SELECT library_book_id, koreader_partial_md5 FROM library_book_format;
+
+COMMENT ON VIEW library_book_file IS 'All document hashes of a book: main file, aliases and additional formats';
+
+CREATE FUNCTION library_book_all_hashes(hash TEXT) RETURNS SETOF TEXT AS $$
+    SELECT hash
+    UNION
+    SELECT d.koreader_partial_md5
+    FROM library_book_file d
+    JOIN library_book_file q ON q.library_book_id = d.library_book_id
+    WHERE q.koreader_partial_md5 = hash
+$$ LANGUAGE SQL STABLE;
+
+COMMENT ON FUNCTION library_book_all_hashes(TEXT) IS 'Resolves document hash to hashes of all files of the same book, used to match stats and reading activity';
+COMMENT ON FUNCTION library_book_hashes(TEXT) IS 'Resolves document hash to all hashes of the same file, used to sync progress';
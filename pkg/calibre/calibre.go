@@ -0,0 +1,226 @@
+// Package calibre reads books from Calibre library directory:
+// metadata.db with curated metadata and Author/Title (id)/ folders with files.
+package calibre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/text/language"
+)
+
+const MetadataFile = "metadata.db"
+
+// Book is a book record of Calibre library.
+type Book struct {
+	ID          int
+	Title       string
+	Authors     []string
+	Series      string
+	SeriesIndex float64
+	Tags        []string
+	Publisher   string
+	Year        int
+	ISBN        string
+	Language    string
+	Comments    string
+	Path        string // folder relative to library root
+	HasCover    bool
+	Formats     []Format
+}
+
+// Format is a file of the book in one format.
+type Format struct {
+	Format string // upper case, e.g. EPUB
+	Name   string // file name without extension
+}
+
+// Filename returns file name of the format inside book folder.
+func (f Format) Filename() string {
+	return f.Name + "." + strings.ToLower(f.Format)
+}
+
+type Library struct {
+	dir string
+	db  *sql.DB
+}
+
+// IsLibrary reports whether directory looks like Calibre library.
+func IsLibrary(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, MetadataFile))
+	return err == nil && !info.IsDir()
+}
+
+// Open opens metadata.db of the library in read-only mode.
+func Open(dir string) (*Library, error) {
+	if !IsLibrary(dir) {
+		return nil, fmt.Errorf("calibre - Open - %s not found in %s", MetadataFile, dir)
+	}
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, MetadataFile)+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("calibre - Open - sql.Open: %w", err)
+	}
+	return &Library{dir: dir, db: db}, nil
+}
+
+func (l *Library) Close() error {
+	return l.db.Close()
+}
+
+// FilePath returns absolute path to the file of the format.
+func (l *Library) FilePath(book Book, format Format) string {
+	return filepath.Join(l.dir, filepath.FromSlash(book.Path), format.Filename())
+}
+
+// CoverPath returns absolute path to the cover of the book.
+func (l *Library) CoverPath(book Book) string {
+	return filepath.Join(l.dir, filepath.FromSlash(book.Path), "cover.jpg")
+}
+
+// Books reads all books with metadata.
+func (l *Library) Books(ctx context.Context) ([]Book, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT id, title, path, has_cover, coalesce(series_index, 0),
+			coalesce(substr(pubdate, 1, 4), ''), coalesce(isbn, '')
+		FROM books
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("calibre - Books - db.Query: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]Book, 0)
+	for rows.Next() {
+		var book Book
+		var year string
+		err = rows.Scan(&book.ID, &book.Title, &book.Path, &book.HasCover, &book.SeriesIndex, &year, &book.ISBN)
+		if err != nil {
+			return nil, fmt.Errorf("calibre - Books - rows.Scan: %w", err)
+		}
+		// calibre marks unknown date with year 101
+		if y, err := strconv.Atoi(year); err == nil && y > 101 {
+			book.Year = y
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("calibre - Books - rows.Err: %w", err)
+	}
+
+	authors, err := l.links(ctx, `SELECT l.book, a.name FROM books_authors_link l JOIN authors a ON a.id = l.author ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	series, err := l.links(ctx, `SELECT l.book, s.name FROM books_series_link l JOIN series s ON s.id = l.series ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := l.links(ctx, `SELECT l.book, t.name FROM books_tags_link l JOIN tags t ON t.id = l.tag ORDER BY t.name`)
+	if err != nil {
+		return nil, err
+	}
+	publishers, err := l.links(ctx, `SELECT l.book, p.name FROM books_publishers_link l JOIN publishers p ON p.id = l.publisher ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	languages, err := l.links(ctx, `SELECT l.book, g.lang_code FROM books_languages_link l JOIN languages g ON g.id = l.lang_code ORDER BY l.item_order`)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := l.links(ctx, `SELECT book, text FROM comments`)
+	if err != nil {
+		return nil, err
+	}
+	isbns, err := l.links(ctx, `SELECT book, val FROM identifiers WHERE type = 'isbn'`)
+	if err != nil {
+		return nil, err
+	}
+	formats, err := l.formats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range books {
+		id := books[i].ID
+		books[i].Authors = authors[id]
+		books[i].Series = first(series[id])
+		books[i].Tags = tags[id]
+		books[i].Publisher = first(publishers[id])
+		books[i].Language = normalizeLanguage(first(languages[id]))
+		books[i].Comments = first(comments[id])
+		if isbn := first(isbns[id]); isbn != "" {
+			books[i].ISBN = isbn
+		}
+		books[i].Formats = formats[id]
+	}
+
+	return books, nil
+}
+
+// links reads (book, value) pairs of many-to-many table into map.
+func (l *Library) links(ctx context.Context, query string) (map[int][]string, error) {
+	rows, err := l.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("calibre - links - db.Query: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[int][]string)
+	for rows.Next() {
+		var book int
+		var value sql.NullString
+		err = rows.Scan(&book, &value)
+		if err != nil {
+			return nil, fmt.Errorf("calibre - links - rows.Scan: %w", err)
+		}
+		if value.Valid && value.String != "" {
+			values[book] = append(values[book], value.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+func (l *Library) formats(ctx context.Context) (map[int][]Format, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT book, format, name FROM data ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("calibre - formats - db.Query: %w", err)
+	}
+	defer rows.Close()
+
+	formats := make(map[int][]Format)
+	for rows.Next() {
+		var book int
+		var format Format
+		err = rows.Scan(&book, &format.Format, &format.Name)
+		if err != nil {
+			return nil, fmt.Errorf("calibre - formats - rows.Scan: %w", err)
+		}
+		formats[book] = append(formats[book], format)
+	}
+	return formats, rows.Err()
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// normalizeLanguage converts ISO 639-2 code of calibre, like "eng", into "en".
+func normalizeLanguage(code string) string {
+	if code == "" {
+		return ""
+	}
+	base, err := language.ParseBase(code)
+	if err != nil {
+		return code
+	}
+	return base.String()
+}
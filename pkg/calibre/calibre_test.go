@@ -0,0 +1,86 @@
+package calibre_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/pkg/calibre"
+)
+
+// schema is a subset of Calibre metadata.db used by reader
+const schema = `
+CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT, path TEXT, has_cover BOOL, series_index REAL, pubdate TIMESTAMP, isbn TEXT);
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER, author INTEGER);
+CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER, series INTEGER);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER, tag INTEGER);
+CREATE TABLE publishers (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books_publishers_link (id INTEGER PRIMARY KEY, book INTEGER, publisher INTEGER);
+CREATE TABLE languages (id INTEGER PRIMARY KEY, lang_code TEXT);
+CREATE TABLE books_languages_link (id INTEGER PRIMARY KEY, book INTEGER, lang_code INTEGER, item_order INTEGER);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, book INTEGER, text TEXT);
+CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER, type TEXT, val TEXT);
+CREATE TABLE data (id INTEGER PRIMARY KEY, book INTEGER, format TEXT, name TEXT);
+
+INSERT INTO books VALUES (1, 'Guards! Guards!', 'Terry Pratchett/Guards! Guards! (1)', 1, 8, '1989-11-01 00:00:00+00:00', '');
+INSERT INTO books VALUES (2, 'Untitled', 'Unknown/Untitled (2)', 0, 1, '0101-01-01 00:00:00+00:00', '');
+INSERT INTO authors VALUES (1, 'Terry Pratchett');
+INSERT INTO books_authors_link VALUES (1, 1, 1);
+INSERT INTO series VALUES (1, 'Discworld');
+INSERT INTO books_series_link VALUES (1, 1, 1);
+INSERT INTO tags VALUES (1, 'Fantasy'), (2, 'Humor');
+INSERT INTO books_tags_link VALUES (1, 1, 2), (2, 1, 1);
+INSERT INTO publishers VALUES (1, 'Gollancz');
+INSERT INTO books_publishers_link VALUES (1, 1, 1);
+INSERT INTO languages VALUES (1, 'eng');
+INSERT INTO books_languages_link VALUES (1, 1, 1, 0);
+INSERT INTO comments VALUES (1, 1, '<p>Dragons in Ankh-Morpork</p>');
+INSERT INTO identifiers VALUES (1, 1, 'isbn', '9780575046061');
+INSERT INTO data VALUES (1, 1, 'EPUB', 'Guards! Guards! - Terry Pratchett');
+`
+
+func TestLibraryBooks(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, calibre.MetadataFile))
+	require.NoError(t, err)
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.True(t, calibre.IsLibrary(dir))
+	lib, err := calibre.Open(dir)
+	require.NoError(t, err)
+	defer lib.Close()
+
+	books, err := lib.Books(context.Background())
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+
+	book := books[0]
+	assert.Equal(t, "Guards! Guards!", book.Title)
+	assert.Equal(t, []string{"Terry Pratchett"}, book.Authors)
+	assert.Equal(t, "Discworld", book.Series)
+	assert.Equal(t, 8.0, book.SeriesIndex)
+	assert.Equal(t, []string{"Fantasy", "Humor"}, book.Tags)
+	assert.Equal(t, "Gollancz", book.Publisher)
+	assert.Equal(t, 1989, book.Year)
+	assert.Equal(t, "9780575046061", book.ISBN)
+	assert.Equal(t, "en", book.Language)
+	assert.Equal(t, "<p>Dragons in Ankh-Morpork</p>", book.Comments)
+	assert.True(t, book.HasCover)
+	require.Len(t, book.Formats, 1)
+	assert.Equal(t,
+		filepath.Join(dir, "Terry Pratchett", "Guards! Guards! (1)", "Guards! Guards! - Terry Pratchett.epub"),
+		lib.FilePath(book, book.Formats[0]))
+
+	assert.Equal(t, 0, books[1].Year)
+	assert.Empty(t, books[1].Formats)
+}
@@ -0,0 +1,95 @@
+// Package converter converts book files to other formats with external programs,
+// e.g. kepubify for Kobo or ebook-convert from Calibre.
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+const (
+	InputPlaceholder  = "{input}"
+	OutputPlaceholder = "{output}"
+
+	// only the tail of long program output is kept in error
+	outputLimit = 1024
+)
+
+// ErrInvalidCommand is returned for command without program or placeholders.
+var ErrInvalidCommand = errors.New("invalid converter command")
+
+// Converter converts a book file to another format.
+type Converter interface {
+	// Format is the format of converted file, e.g. kepub.
+	Format() string
+	// Accepts tells if the file of the format can be converted.
+	Accepts(format string) bool
+	// Convert reads input file and writes converted file to output path.
+	Convert(ctx context.Context, input, output string) error
+}
+
+// Command runs external program, {input} and {output} in its arguments
+// are replaced with paths of source and converted files.
+// Arguments are split by spaces, quoting is not supported.
+type Command struct {
+	format string
+	from   []string
+	args   []string
+}
+
+func NewCommand(format string, from []string, command string) (*Command, error) {
+	args := strings.Fields(command)
+	if format == "" || len(args) == 0 ||
+		!strings.Contains(command, InputPlaceholder) || !strings.Contains(command, OutputPlaceholder) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCommand, command)
+	}
+	return &Command{format: format, from: from, args: args}, nil
+}
+
+func (c *Command) Format() string {
+	return c.format
+}
+
+func (c *Command) Accepts(format string) bool {
+	return slices.Contains(c.from, format)
+}
+
+func (c *Command) Convert(ctx context.Context, input, output string) error {
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		arg = strings.ReplaceAll(arg, InputPlaceholder, input)
+		args[i] = strings.ReplaceAll(arg, OutputPlaceholder, output)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, tail(out.String()))
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return fmt.Errorf("%s: no converted file: %w", args[0], err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("%s: converted file is empty", args[0])
+	}
+	return nil
+}
+
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > outputLimit {
+		s = "..." + s[len(s)-outputLimit:]
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package converter_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/pkg/converter"
+)
+
+// fakeScript copies input to output with a marker, or fails on "broken" input.
+const fakeScript = `#!/bin/sh
+if grep -q broken "$2"; then
+	echo "cannot parse $2" >&2
+	exit 1
+fi
+{ cat "$2"; echo converted; } > "$1"
+`
+
+func writeScript(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "fake-convert")
+	require.NoError(t, os.WriteFile(path, []byte(fakeScript), 0o755))
+	return path
+}
+
+func TestNewCommand(t *testing.T) {
+	_, err := converter.NewCommand("kepub", []string{"epub"}, "kepubify {input}")
+	assert.ErrorIs(t, err, converter.ErrInvalidCommand)
+	_, err = converter.NewCommand("kepub", []string{"epub"}, "")
+	assert.ErrorIs(t, err, converter.ErrInvalidCommand)
+
+	c, err := converter.NewCommand("kepub", []string{"epub"}, "kepubify -o {output} {input}")
+	require.NoError(t, err)
+	assert.Equal(t, "kepub", c.Format())
+	assert.True(t, c.Accepts("epub"))
+	assert.False(t, c.Accepts("pdf"))
+}
+
+func TestCommandConvert(t *testing.T) {
+	c, err := converter.NewCommand("kepub", []string{"epub"}, writeScript(t)+" {output} {input}")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "book.epub")
+	output := filepath.Join(dir, "book.kepub.epub")
+	require.NoError(t, os.WriteFile(input, []byte("book\n"), 0o644))
+
+	require.NoError(t, c.Convert(context.Background(), input, output))
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "book\nconverted\n", string(data))
+
+	require.NoError(t, os.WriteFile(input, []byte("broken\n"), 0o644))
+	err = c.Convert(context.Background(), input, filepath.Join(dir, "broken.kepub.epub"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot parse")
+}
@@ -0,0 +1,232 @@
+package metadata
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+// Comic book archives are ZIP (cbz) or RAR (cbr) archives with page images
+// and optional ComicInfo.xml from ComicRack.
+// See https://anansi-project.github.io/docs/comicinfo/intro
+const (
+	comicInfoFile = "comicinfo.xml"
+	rarMagic      = "Rar!\x1a\x07"
+)
+
+var errNotComic = errors.New("no images in comic archive")
+
+type ComicInfo struct {
+	Title       string `xml:"Title"`
+	Series      string `xml:"Series"`
+	Number      string `xml:"Number"`
+	Summary     string `xml:"Summary"`
+	Year        int    `xml:"Year"`
+	Writer      string `xml:"Writer"`
+	Publisher   string `xml:"Publisher"`
+	LanguageISO string `xml:"LanguageISO"`
+	GTIN        string `xml:"GTIN"`
+	Pages       []struct {
+		Image int    `xml:"Image,attr"`
+		Type  string `xml:"Type,attr"`
+	} `xml:"Pages>Page"`
+}
+
+// isImage reports whether archive entry is an image, hidden files
+// like __MACOSX/._page.jpg are skipped.
+func isImage(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(base)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
+// isComicZip reports whether ZIP archive is mostly page images,
+// so archives of books with a few covers are not taken for comics.
+func isComicZip(reader *zip.Reader) bool {
+	images, files := 0, 0
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files++
+		if isImage(f.Name) {
+			images++
+		}
+	}
+	return images > 0 && images*2 > files
+}
+
+func getCbzMetadata(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var comicInfo []byte
+	pages := make(map[string]*zip.File)
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if strings.ToLower(path.Base(f.Name)) == comicInfoFile {
+			comicInfo, _ = readFileContent(f)
+		} else if isImage(f.Name) {
+			pages[f.Name] = f
+		}
+	}
+	if len(pages) == 0 {
+		return Metadata{}, errNotComic
+	}
+
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := parseComicInfo(comicInfo)
+	m.Pages = len(names)
+	cover, err := readFileContent(pages[coverPage(names, comicInfo)])
+	if err == nil {
+		m.Cover = cover
+	}
+	return m, nil
+}
+
+func getCbrMetadata(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	// RAR is read sequentially, so the cover is read on the second pass,
+	// when all page names are known
+	var comicInfo []byte
+	var pages []string
+	err = walkRar(io.NewSectionReader(file, 0, info.Size()), func(name string, r io.Reader) error {
+		if strings.ToLower(path.Base(name)) == comicInfoFile {
+			comicInfo, _ = io.ReadAll(r)
+		} else if isImage(name) {
+			pages = append(pages, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return Metadata{}, err
+	}
+	if len(pages) == 0 {
+		return Metadata{}, errNotComic
+	}
+	sort.Strings(pages)
+
+	m := parseComicInfo(comicInfo)
+	m.Pages = len(pages)
+	cover := coverPage(pages, comicInfo)
+	err = walkRar(io.NewSectionReader(file, 0, info.Size()), func(name string, r io.Reader) error {
+		if name != cover {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		m.Cover = data
+		return io.EOF
+	})
+	if err != nil {
+		return Metadata{}, err
+	}
+	return m, nil
+}
+
+// walkRar calls fn for every file of RAR archive until it returns error,
+// io.EOF stops walking without error.
+func walkRar(r io.Reader, fn func(name string, r io.Reader) error) error {
+	reader, err := rardecode.NewReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			return ignoreEOF(err)
+		}
+		if header.IsDir {
+			continue
+		}
+		err = fn(header.Name, reader)
+		if err != nil {
+			return ignoreEOF(err)
+		}
+	}
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// parseComicInfo maps ComicInfo.xml fields, title falls back to series and number.
+func parseComicInfo(data []byte) Metadata {
+	var info ComicInfo
+	if len(data) == 0 || xml.Unmarshal(data, &info) != nil {
+		return Metadata{}
+	}
+
+	m := Metadata{
+		Title:       strings.TrimSpace(info.Title),
+		Description: strings.TrimSpace(info.Summary),
+		Author:      strings.TrimSpace(info.Writer),
+		Publisher:   strings.TrimSpace(info.Publisher),
+		Language:    strings.TrimSpace(info.LanguageISO),
+		ISBN:        strings.TrimSpace(info.GTIN),
+		Series:      strings.TrimSpace(info.Series),
+	}
+	number := strings.TrimSpace(info.Number)
+	if index, err := strconv.ParseFloat(number, 64); err == nil {
+		m.SeriesIndex = index
+	}
+	if m.Title == "" && m.Series != "" {
+		m.Title = m.Series
+		if number != "" {
+			m.Title += " #" + number
+		}
+	}
+	if info.Year > 0 {
+		m.Date = strconv.Itoa(info.Year)
+	}
+	return m
+}
+
+// coverPage picks page marked as FrontCover in ComicInfo.xml or the first one.
+func coverPage(names []string, comicInfo []byte) string {
+	var info ComicInfo
+	if len(comicInfo) > 0 && xml.Unmarshal(comicInfo, &info) == nil {
+		for _, page := range info.Pages {
+			if page.Type == "FrontCover" && page.Image >= 0 && page.Image < len(names) {
+				return names[page.Image]
+			}
+		}
+	}
+	return names[0]
+}
@@ -0,0 +1,160 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DjVu is an IFF85 file: "AT&T" magic and FORM:DJVU chunk for single page
+// or FORM:DJVM with FORM:DJVU per page for bundled documents.
+// Metadata is stored in annotation chunks, pages are wavelet encoded
+// and only backgrounds in BGjp chunks are plain JPEG images.
+// See https://www.sndjvu.org/spec.html
+const (
+	djvuMagic     = "AT&TFORM"
+	iffHeaderSize = 8
+)
+
+var (
+	errNotDjvu = errors.New("not a DjVu file")
+
+	djvuMetadataRe = regexp.MustCompile(`\((title|author|publisher|year|isbn)\s+("(?:[^"\\]|\\.)*")\s*\)`)
+)
+
+// isDjvu checks magic and form type of the file header.
+func isDjvu(header []byte) bool {
+	if len(header) < 16 || string(header[:8]) != djvuMagic {
+		return false
+	}
+	form := string(header[12:16])
+	return form == "DJVU" || form == "DJVM"
+}
+
+type iffChunk struct {
+	id     string
+	offset int64 // offset of chunk data
+	size   int64
+}
+
+// iffChunks lists chunks between start and end, FORM chunks are flattened,
+// so pages of a bundled document follow each other.
+func iffChunks(r io.ReaderAt, start, end int64) ([]iffChunk, error) {
+	var chunks []iffChunk
+	header := make([]byte, iffHeaderSize)
+	for pos := start; pos+iffHeaderSize <= end; {
+		_, err := r.ReadAt(header, pos)
+		if err != nil {
+			return nil, err
+		}
+		chunk := iffChunk{
+			id:     string(header[:4]),
+			offset: pos + iffHeaderSize,
+			size:   int64(binary.BigEndian.Uint32(header[4:])),
+		}
+		if chunk.offset+chunk.size > end {
+			return nil, errNotDjvu
+		}
+		if chunk.id == "FORM" && chunk.size >= 4 {
+			form := make([]byte, 4)
+			_, err = r.ReadAt(form, chunk.offset)
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, iffChunk{id: "FORM:" + string(form), offset: chunk.offset, size: chunk.size})
+			nested, err := iffChunks(r, chunk.offset+4, chunk.offset+chunk.size)
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, nested...)
+		} else {
+			chunks = append(chunks, chunk)
+		}
+		// chunks are aligned to even offsets
+		pos = chunk.offset + chunk.size + chunk.size%2
+	}
+	return chunks, nil
+}
+
+func getDjvuMetadata(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	header := make([]byte, 16)
+	_, err = file.ReadAt(header, 0)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if !isDjvu(header) {
+		return Metadata{}, errNotDjvu
+	}
+	// skip "AT&T" magic before the root FORM
+	chunks, err := iffChunks(file, 4, info.Size())
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	pages := 0
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "FORM:DJVU":
+			pages++
+		case "ANTa":
+			// compressed ANTz annotations are not supported
+			data, err := readChunk(file, chunk)
+			if err == nil {
+				parseDjvuAnnotations(string(data), &m)
+			}
+		case "BGjp":
+			if pages == 1 && m.Cover == nil {
+				m.Cover, _ = readChunk(file, chunk)
+			}
+		}
+	}
+	m.Pages = pages
+	return m, nil
+}
+
+func readChunk(r io.ReaderAt, chunk iffChunk) ([]byte, error) {
+	data := make([]byte, chunk.size)
+	_, err := r.ReadAt(data, chunk.offset)
+	return data, err
+}
+
+// parseDjvuAnnotations fills metadata from (metadata (key "value") ...) expression,
+// values already set by previous annotations are kept.
+func parseDjvuAnnotations(annotations string, m *Metadata) {
+	start := strings.Index(annotations, "(metadata")
+	if start < 0 {
+		return
+	}
+	for _, match := range djvuMetadataRe.FindAllStringSubmatch(annotations[start:], -1) {
+		value, err := strconv.Unquote(match[2])
+		if err != nil {
+			value = strings.Trim(match[2], `"`)
+		}
+		value = strings.TrimSpace(value)
+		var field *string
+		switch match[1] {
+		case "title":
+			field = &m.Title
+		case "author":
+			field = &m.Author
+		case "publisher":
+			field = &m.Publisher
+		case "year":
+			field = &m.Date
+		case "isbn":
+			field = &m.ISBN
+		}
+		if *field == "" {
+			*field = value
+		}
+	}
+}
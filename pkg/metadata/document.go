@@ -0,0 +1,266 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/vanadium23/kompanion/pkg/utils"
+)
+
+// Documents have no dedicated container, their metadata is best effort:
+// text headers, HTML head, RTF info group and DOCX core properties.
+const (
+	rtfMagic         = `{\rtf`
+	docxDocument     = "word/document.xml"
+	docxCore         = "docProps/core.xml"
+	textHeaderLimit  = 64 * 1024
+	textHeaderFields = 100
+)
+
+var (
+	rtfInfoRe    = regexp.MustCompile(`\{\\(title|author|operator|subject|doccomm)\s+((?:[^{}\\]|\\.)*)\}`)
+	rtfYearRe    = regexp.MustCompile(`\{\\creatim\\yr(\d{4})`)
+	rtfEscapeRe  = regexp.MustCompile(`\\'([0-9a-fA-F]{2})|\\u(-?\d+)\??|\\([{}\\])`)
+	textFieldsRe = regexp.MustCompile(`(?i)^(title|author|language|date|description|release date)\s*:\s*(.+)$`)
+)
+
+// textFormat chooses format of plain text file by its extension,
+// there is no magic bytes to tell them apart.
+func textFormat(ext string) string {
+	switch ext {
+	case "txt", "text":
+		return "txt"
+	case "md", "markdown":
+		return "md"
+	case "html", "htm", "xhtml":
+		return "html"
+	default:
+		return ""
+	}
+}
+
+// isDocx reports whether ZIP archive is Office Open XML document.
+func isDocx(reader *zip.Reader) bool {
+	for _, f := range reader.File {
+		if f.Name == docxDocument {
+			return true
+		}
+	}
+	return false
+}
+
+// getTextMetadata reads "Key: value" lines at the start of plain text,
+// like in Project Gutenberg books, or in front matter of Markdown.
+// Markdown title falls back to the first heading.
+func getTextMetadata(file *os.File) (Metadata, error) {
+	scanner := bufio.NewScanner(io.NewSectionReader(file, 0, textHeaderLimit))
+	var m Metadata
+	heading := ""
+	for i := 0; i < textHeaderFields && scanner.Scan(); i++ {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if heading == "" && strings.HasPrefix(line, "# ") {
+			heading = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+		match := textFieldsRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(match[2]), `"'`)
+		var field *string
+		switch strings.ToLower(match[1]) {
+		case "title":
+			field = &m.Title
+		case "author":
+			field = &m.Author
+		case "language":
+			field = &m.Language
+		case "date", "release date":
+			field = &m.Date
+		case "description":
+			field = &m.Description
+		}
+		if *field == "" {
+			*field = value
+		}
+	}
+	if m.Title == "" {
+		m.Title = heading
+	}
+	return m, nil
+}
+
+// getHtmlMetadata reads title and meta tags of HTML head.
+func getHtmlMetadata(file *os.File) (Metadata, error) {
+	reader, err := charset.NewReader(io.NewSectionReader(file, 0, textHeaderLimit), "text/html")
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	tokenizer := html.NewTokenizer(reader)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return m, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				return m, nil
+			case "html":
+				m.Language = htmlAttr(token, "lang")
+			case "title":
+				if tokenizer.Next() == html.TextToken && m.Title == "" {
+					m.Title = strings.TrimSpace(tokenizer.Token().Data)
+				}
+			case "meta":
+				applyHtmlMeta(&m, strings.ToLower(htmlAttr(token, "name")), htmlAttr(token, "content"))
+				applyHtmlMeta(&m, strings.ToLower(htmlAttr(token, "property")), htmlAttr(token, "content"))
+			}
+		}
+	}
+}
+
+func applyHtmlMeta(m *Metadata, name, content string) {
+	content = strings.TrimSpace(content)
+	if name == "" || content == "" {
+		return
+	}
+	switch name {
+	case "dc.title", "og:title":
+		m.Title = content
+	case "author", "dc.creator":
+		m.Author = content
+	case "description", "dc.description", "og:description":
+		if m.Description == "" {
+			m.Description = content
+		}
+	case "dc.publisher", "og:site_name":
+		if m.Publisher == "" {
+			m.Publisher = content
+		}
+	case "dc.date", "article:published_time":
+		m.Date = content
+	case "dc.language":
+		m.Language = content
+	}
+}
+
+func htmlAttr(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return strings.TrimSpace(attr.Val)
+		}
+	}
+	return ""
+}
+
+// getRtfMetadata reads info group from RTF header.
+func getRtfMetadata(file *os.File) (Metadata, error) {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, textHeaderLimit))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	for _, match := range rtfInfoRe.FindAllStringSubmatch(string(data), -1) {
+		value := strings.TrimSpace(rtfUnescape(match[2]))
+		switch match[1] {
+		case "title":
+			m.Title = value
+		case "author":
+			m.Author = value
+		case "operator":
+			if m.Author == "" {
+				m.Author = value
+			}
+		case "doccomm", "subject":
+			if m.Description == "" {
+				m.Description = value
+			}
+		}
+	}
+	if match := rtfYearRe.FindStringSubmatch(string(data)); match != nil {
+		m.Date = match[1]
+	}
+	return m, nil
+}
+
+// rtfUnescape decodes \'hh (Windows-1252) and \uN escapes.
+func rtfUnescape(s string) string {
+	return rtfEscapeRe.ReplaceAllStringFunc(s, func(escape string) string {
+		match := rtfEscapeRe.FindStringSubmatch(escape)
+		switch {
+		case match[1] != "":
+			b, _ := strconv.ParseUint(match[1], 16, 8)
+			return string(charmap.Windows1252.DecodeByte(byte(b)))
+		case match[2] != "":
+			// negative values are used for code points above 32767
+			code, _ := strconv.Atoi(match[2])
+			if code < 0 {
+				code += 65536
+			}
+			return string(rune(code))
+		default:
+			return match[3]
+		}
+	})
+}
+
+type docxCoreProperties struct {
+	Title       string `xml:"title"`
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+	Subject     string `xml:"subject"`
+	Language    string `xml:"language"`
+	Created     string `xml:"created"`
+}
+
+// getDocxMetadata reads docProps/core.xml and thumbnail of the document.
+func getDocxMetadata(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	for _, f := range reader.File {
+		switch {
+		case f.Name == docxCore:
+			data, err := readFileContent(f)
+			if err != nil {
+				return Metadata{}, err
+			}
+			var core docxCoreProperties
+			err = xml.Unmarshal(data, &core)
+			if err != nil {
+				return Metadata{}, err
+			}
+			m.Title = strings.TrimSpace(core.Title)
+			m.Author = strings.TrimSpace(core.Creator)
+			m.Description = strings.TrimSpace(utils.If(core.Description != "", core.Description, core.Subject))
+			m.Language = strings.TrimSpace(core.Language)
+			m.Date = strings.TrimSpace(core.Created)
+			if len(m.Date) > len("2006-01-02") {
+				m.Date = m.Date[:len("2006-01-02")]
+			}
+		case strings.HasPrefix(f.Name, "docProps/thumbnail.") && isImage(f.Name):
+			m.Cover, _ = readFileContent(f)
+		}
+	}
+	return m, nil
+}
@@ -3,9 +3,11 @@ package metadata
 import (
 	"archive/zip"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -24,17 +26,22 @@ type Container struct {
 // content.opf struct
 type EpubMetadata struct {
 	Metadata struct {
-		ISBN        string `xml:"identifier"`
-		Title       string `xml:"title"`
-		Description string `xml:"description"`
-		Creator     string `xml:"creator"`
-		Date        string `xml:"date"`
-		Publisher   string `xml:"publisher"`
-		Language    string `xml:"language"`
-		Format      string `xml:"format"`
+		Identifiers []EpubText `xml:"identifier"`
+		Titles      []EpubText `xml:"title"`
+		Description string     `xml:"description"`
+		Creators    []EpubText `xml:"creator"`
+		Dates       []EpubText `xml:"date"`
+		Publisher   string     `xml:"publisher"`
+		Language    string     `xml:"language"`
+		Format      string     `xml:"format"`
+		Subjects    []string   `xml:"subject"`
 		Meta        []struct {
-			Name    string `xml:"name,attr"`
-			Content string `xml:"content,attr"`
+			ID       string `xml:"id,attr"`
+			Name     string `xml:"name,attr"`
+			Content  string `xml:"content,attr"`
+			Property string `xml:"property,attr"`
+			Refines  string `xml:"refines,attr"`
+			Value    string `xml:",chardata"`
 		} `xml:"meta"`
 	} `xml:"metadata"`
 	Manifest struct {
@@ -61,7 +68,13 @@ func getEpubMetadata(tmpFile *os.File) (Metadata, error) {
 
 	for _, f := range reader.File {
 		if f.Name == "META-INF/container.xml" {
-			container, _ := parseContainerXML(f)
+			container, err := parseContainerXML(f)
+			if err != nil {
+				return Metadata{}, fmt.Errorf("parseContainerXML: %w", err)
+			}
+			if len(container.Rootfiles) == 0 {
+				return Metadata{}, errors.New("container.xml has no rootfile")
+			}
 			metadataFilepath = container.Rootfiles[0].FullPath
 			break
 		}
@@ -76,19 +89,151 @@ func getEpubMetadata(tmpFile *os.File) (Metadata, error) {
 	}
 
 	cover := findEpubCover(reader, metadata)
+	refinements := metadata.refinements()
+	series, seriesIndex := metadata.series(refinements)
 
 	return Metadata{
-		ISBN:        metadata.Metadata.ISBN,
-		Title:       metadata.Metadata.Title,
-		Description: metadata.Metadata.Description,
-		Author:      metadata.Metadata.Creator,
-		Date:        metadata.Metadata.Date,
-		Publisher:   metadata.Metadata.Publisher,
-		Language:    metadata.Metadata.Language,
+		ISBN:        metadata.isbn(),
+		Title:       metadata.title(refinements),
+		Description: strings.TrimSpace(metadata.Metadata.Description),
+		Author:      metadata.authors(refinements),
+		Date:        metadata.date(),
+		Publisher:   strings.TrimSpace(metadata.Metadata.Publisher),
+		Language:    strings.TrimSpace(metadata.Metadata.Language),
+		Series:      series,
+		SeriesIndex: seriesIndex,
+		Subjects:    metadata.subjects(),
 		Cover:       cover,
 	}, nil
 }
 
+// EpubText is a Dublin Core element with EPUB2 opf: attributes,
+// in EPUB3 the same information is in meta elements refining it by id.
+type EpubText struct {
+	ID     string `xml:"id,attr"`
+	Role   string `xml:"role,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Event  string `xml:"event,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// refinements collects EPUB3 meta refines="#id" property values by id.
+func (e EpubMetadata) refinements() map[string]map[string]string {
+	refinements := make(map[string]map[string]string)
+	for _, meta := range e.Metadata.Meta {
+		if meta.Refines == "" || meta.Property == "" {
+			continue
+		}
+		id := strings.TrimPrefix(meta.Refines, "#")
+		if refinements[id] == nil {
+			refinements[id] = make(map[string]string)
+		}
+		refinements[id][meta.Property] = strings.TrimSpace(meta.Value)
+	}
+	return refinements
+}
+
+// isbn prefers identifier marked as ISBN and falls back to the first one.
+func (e EpubMetadata) isbn() string {
+	for _, id := range e.Metadata.Identifiers {
+		value := strings.TrimSpace(id.Value)
+		if strings.EqualFold(id.Scheme, "isbn") {
+			return value
+		}
+		if strings.HasPrefix(strings.ToLower(value), "urn:isbn:") {
+			return value[len("urn:isbn:"):]
+		}
+	}
+	if len(e.Metadata.Identifiers) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(e.Metadata.Identifiers[0].Value)
+}
+
+// title prefers EPUB3 main title over subtitles and collection titles.
+func (e EpubMetadata) title(refinements map[string]map[string]string) string {
+	for _, title := range e.Metadata.Titles {
+		if title.ID != "" && refinements[title.ID]["title-type"] == "main" {
+			return strings.TrimSpace(title.Value)
+		}
+	}
+	if len(e.Metadata.Titles) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(e.Metadata.Titles[0].Value)
+}
+
+// authors joins creators with author role, or all of them if no roles are set.
+func (e EpubMetadata) authors(refinements map[string]map[string]string) string {
+	var authors, creators []string
+	for _, creator := range e.Metadata.Creators {
+		name := strings.TrimSpace(creator.Value)
+		if name == "" {
+			continue
+		}
+		creators = append(creators, name)
+		role := creator.Role
+		if creator.ID != "" && refinements[creator.ID]["role"] != "" {
+			role = refinements[creator.ID]["role"]
+		}
+		if role == "" || role == "aut" {
+			authors = append(authors, name)
+		}
+	}
+	if len(authors) == 0 {
+		authors = creators
+	}
+	return strings.Join(authors, ", ")
+}
+
+// date prefers publication date of EPUB2 opf:event.
+func (e EpubMetadata) date() string {
+	for _, date := range e.Metadata.Dates {
+		if date.Event == "" || date.Event == "publication" {
+			return strings.TrimSpace(date.Value)
+		}
+	}
+	if len(e.Metadata.Dates) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(e.Metadata.Dates[0].Value)
+}
+
+// series reads EPUB3 belongs-to-collection or calibre:series metadata.
+func (e EpubMetadata) series(refinements map[string]map[string]string) (string, float64) {
+	var calibreSeries, calibreIndex string
+	for _, meta := range e.Metadata.Meta {
+		switch {
+		case meta.Property == "belongs-to-collection" && meta.Refines == "":
+			refined := refinements[meta.ID]
+			if kind := refined["collection-type"]; kind != "" && kind != "series" {
+				continue
+			}
+			index, _ := strconv.ParseFloat(refined["group-position"], 64)
+			return strings.TrimSpace(meta.Value), index
+		case meta.Name == "calibre:series":
+			calibreSeries = strings.TrimSpace(meta.Content)
+		case meta.Name == "calibre:series_index":
+			calibreIndex = strings.TrimSpace(meta.Content)
+		}
+	}
+	index, _ := strconv.ParseFloat(calibreIndex, 64)
+	return calibreSeries, index
+}
+
+func (e EpubMetadata) subjects() []string {
+	var subjects []string
+	seen := make(map[string]bool)
+	for _, subject := range e.Metadata.Subjects {
+		subject = strings.TrimSpace(subject)
+		if subject != "" && !seen[subject] {
+			seen[subject] = true
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects
+}
+
 func findEpubCover(reader *zip.Reader, metadata EpubMetadata) []byte {
 	var coverID string
 	for _, meta := range metadata.Metadata.Meta {
@@ -123,7 +268,7 @@ func parseContainerXML(f *zip.File) (Container, error) {
 		return Container{}, err
 	}
 
-	return unmarshalContainerXML(byteValue), nil
+	return unmarshalContainerXML(byteValue)
 }
 
 func readFileContent(f *zip.File) ([]byte, error) {
@@ -147,8 +292,8 @@ func unmarshalMetaDataXML(byteValue []byte) EpubMetadata {
 	return meta
 }
 
-func unmarshalContainerXML(byteValue []byte) Container {
+func unmarshalContainerXML(byteValue []byte) (Container, error) {
 	var container Container
-	xml.Unmarshal(byteValue, &container)
-	return container
+	err := xml.Unmarshal(byteValue, &container)
+	return container, err
 }
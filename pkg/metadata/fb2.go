@@ -1,14 +1,21 @@
 package metadata
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding/charmap"
+
+	"github.com/vanadium23/kompanion/pkg/utils"
 )
 
 // FictionBook struct for root element
@@ -31,8 +38,16 @@ type Description struct {
 
 // TitleInfo struct holds title metadata
 type TitleInfo struct {
-	XMLName   xml.Name `xml:"title-info"`
-	BookTitle string   `xml:"book-title"`
+	XMLName    xml.Name `xml:"title-info"`
+	BookTitle  string   `xml:"book-title"`
+	Authors    []Author `xml:"author"`
+	Annotation []string `xml:"annotation>p"`
+	Date       string   `xml:"date"`
+	Lang       string   `xml:"lang"`
+	Sequence   struct {
+		Name   string `xml:"name,attr"`
+		Number string `xml:"number,attr"`
+	} `xml:"sequence"`
 	Coverpage struct {
 		Image struct {
 			Href string `xml:"href,attr"`
@@ -45,22 +60,48 @@ type PubInfo struct {
 	XMLName   xml.Name `xml:"publish-info"`
 	Publisher string   `xml:"publisher"`
 	Year      string   `xml:"year"`
+	ISBN      string   `xml:"isbn"`
 }
 
 // Author struct for author information
 type Author struct {
-	XMLName   xml.Name `xml:"author"`
-	FirstName string   `xml:"first-name"`
-	LastName  string   `xml:"last-name"`
+	XMLName    xml.Name `xml:"author"`
+	FirstName  string   `xml:"first-name"`
+	MiddleName string   `xml:"middle-name"`
+	LastName   string   `xml:"last-name"`
+	Nickname   string   `xml:"nickname"`
+}
+
+// Name joins name parts, nickname is used for authors without name.
+func (a Author) Name() string {
+	var parts []string
+	for _, part := range []string{a.FirstName, a.MiddleName, a.LastName} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return strings.TrimSpace(a.Nickname)
+	}
+	return strings.Join(parts, " ")
 }
 
-func getFb2Metatada(tmpFile *os.File) (Metadata, error) {
+func getFb2Metatada(r io.Reader) (Metadata, error) {
 	// Parse the XML data
-	d := xml.NewDecoder(tmpFile)
+	d := xml.NewDecoder(r)
 	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
 		switch charset {
 		case "windows-1251":
-			return charmap.Windows1251.NewDecoder().Reader(input), nil
+			data, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			// some converters declare windows-1251, but write UTF-8,
+			// Cyrillic text in windows-1251 is almost never valid UTF-8
+			if utf8.Valid(data) {
+				return bytes.NewReader(data), nil
+			}
+			return charmap.Windows1251.NewDecoder().Reader(bytes.NewReader(data)), nil
 		default:
 			return nil, fmt.Errorf("unknown charset: %s", charset)
 		}
@@ -76,13 +117,75 @@ func getFb2Metatada(tmpFile *os.File) (Metadata, error) {
 		fmt.Println("Error finding cover:", err)
 	}
 
+	titleInfo := book.Description.Title
+	var authors []string
+	for _, author := range titleInfo.Authors {
+		if name := author.Name(); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	var annotation []string
+	for _, p := range titleInfo.Annotation {
+		if p = strings.TrimSpace(p); p != "" {
+			annotation = append(annotation, p)
+		}
+	}
+	seriesIndex, _ := strconv.ParseFloat(titleInfo.Sequence.Number, 64)
+
 	return Metadata{
-		Title:     book.Description.Title.BookTitle,
-		Publisher: book.Description.Publish.Publisher,
-		Cover:     cover,
+		Title:       titleInfo.BookTitle,
+		Author:      strings.Join(authors, ", "),
+		Description: strings.Join(annotation, "\n"),
+		Date:        utils.If(titleInfo.Date != "", titleInfo.Date, book.Description.Publish.Year),
+		Language:    titleInfo.Lang,
+		Series:      strings.TrimSpace(titleInfo.Sequence.Name),
+		SeriesIndex: seriesIndex,
+		ISBN:        book.Description.Publish.ISBN,
+		Publisher:   book.Description.Publish.Publisher,
+		Cover:       cover,
 	}, nil
 }
 
+// isFb2Zip reports whether ZIP archive holds a single FB2 book, as .fb2.zip files do.
+func isFb2Zip(reader *zip.Reader) bool {
+	return fb2ZipEntry(reader) != nil
+}
+
+func fb2ZipEntry(reader *zip.Reader) *zip.File {
+	var entry *zip.File
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), ".") || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		if entry != nil || !strings.EqualFold(path.Ext(f.Name), ".fb2") {
+			return nil
+		}
+		entry = f
+	}
+	return entry
+}
+
+func getFb2ZipMetadata(file *os.File) (Metadata, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return Metadata{}, err
+	}
+	entry := fb2ZipEntry(reader)
+	if entry == nil {
+		return Metadata{}, fmt.Errorf("no fb2 file in archive")
+	}
+	rc, err := entry.Open()
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer rc.Close()
+	return getFb2Metatada(rc)
+}
+
 func findFB2Cover(metadata FictionBook) ([]byte, error) {
 	coverHref := metadata.Description.Title.Coverpage.Image.Href
 	if coverHref == "" {
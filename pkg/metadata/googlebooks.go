@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Google Books volumes search works without API key, but key gives bigger quota.
+// See https://developers.google.com/books/docs/v1/using
+const GoogleBooksURL = "https://www.googleapis.com"
+
+type googleBooksVolumes struct {
+	Items []struct {
+		VolumeInfo struct {
+			Title               string   `json:"title"`
+			Subtitle            string   `json:"subtitle"`
+			Authors             []string `json:"authors"`
+			Publisher           string   `json:"publisher"`
+			PublishedDate       string   `json:"publishedDate"`
+			Description         string   `json:"description"`
+			PageCount           int      `json:"pageCount"`
+			Categories          []string `json:"categories"`
+			Language            string   `json:"language"`
+			IndustryIdentifiers []struct {
+				Type       string `json:"type"`
+				Identifier string `json:"identifier"`
+			} `json:"industryIdentifiers"`
+			ImageLinks map[string]string `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+// googleBooksCovers is an order in which image links are tried, the largest first.
+var googleBooksCovers = []string{"large", "medium", "small", "thumbnail", "smallThumbnail"}
+
+// GoogleBooks looks up books in Google Books catalog.
+type GoogleBooks struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewGoogleBooks creates provider, empty URL is replaced with public one.
+func NewGoogleBooks(baseURL, apiKey string, client *http.Client) *GoogleBooks {
+	if baseURL == "" {
+		baseURL = GoogleBooksURL
+	}
+	return &GoogleBooks{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  defaultClient(client),
+	}
+}
+
+func (p *GoogleBooks) Name() string {
+	return "googlebooks"
+}
+
+func (p *GoogleBooks) Lookup(ctx context.Context, query Query) (Metadata, error) {
+	var q string
+	if query.ISBN != "" {
+		q = "isbn:" + normalizeISBN(query.ISBN)
+	} else {
+		q = fmt.Sprintf("intitle:%q", query.Title)
+		if query.Author != "" {
+			q += fmt.Sprintf(" inauthor:%q", query.Author)
+		}
+	}
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("maxResults", "1")
+	if p.apiKey != "" {
+		params.Set("key", p.apiKey)
+	}
+
+	var volumes googleBooksVolumes
+	err := getJSON(ctx, p.client, p.baseURL+"/books/v1/volumes?"+params.Encode(), &volumes)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("GoogleBooks - Lookup - volumes: %w", err)
+	}
+	if len(volumes.Items) == 0 {
+		return Metadata{}, ErrNotFound
+	}
+	info := volumes.Items[0].VolumeInfo
+
+	m := Metadata{
+		Title:       strings.TrimSpace(info.Title),
+		Author:      joinTrimmed(info.Authors, ", "),
+		Publisher:   strings.TrimSpace(info.Publisher),
+		Date:        strings.TrimSpace(info.PublishedDate),
+		Description: strings.TrimSpace(info.Description),
+		Language:    strings.TrimSpace(info.Language),
+		Subjects:    info.Categories,
+		Pages:       info.PageCount,
+	}
+	if info.Subtitle != "" {
+		m.Title += ": " + strings.TrimSpace(info.Subtitle)
+	}
+	var isbns []string
+	for _, id := range info.IndustryIdentifiers {
+		if strings.HasPrefix(id.Type, "ISBN") {
+			isbns = append(isbns, id.Identifier)
+		}
+	}
+	m.ISBN = pickISBN(query.ISBN, isbns)
+
+	for _, size := range googleBooksCovers {
+		link := info.ImageLinks[size]
+		if link == "" {
+			continue
+		}
+		// links are http and have page curl effect by default
+		if strings.HasPrefix(link, "http://books.google.") {
+			link = "https://" + strings.TrimPrefix(link, "http://")
+		}
+		link = strings.Replace(link, "&edge=curl", "", 1)
+		m.Cover, err = getCover(ctx, p.client, link)
+		if err != nil {
+			return Metadata{}, fmt.Errorf("GoogleBooks - Lookup - cover: %w", err)
+		}
+		break
+	}
+	return m, nil
+}
@@ -1,10 +1,16 @@
 package metadata
 
 import (
+	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 type Metadata struct {
@@ -15,16 +21,34 @@ type Metadata struct {
 	Date        string
 	Publisher   string
 	Language    string
+	Series      string
+	SeriesIndex float64
+	Subjects    []string
+	Pages       int
 	Format      string
 	Cover       []byte
 }
 
-// ExtractBookMetadata extracts metadata from a book file
-func ExtractBookMetadata(tempFile *os.File) (Metadata, error) {
-	extension, err := guessExtention(tempFile)
+var yearRe = regexp.MustCompile(`\d{4}`)
+
+// Year returns the first four digit number of Date,
+// as books carry dates in any format from "1860-1861" to "2016-01-03".
+func (m Metadata) Year() int {
+	year, _ := strconv.Atoi(yearRe.FindString(m.Date))
+	return year
+}
+
+// ExtractBookMetadata extracts metadata from a book file,
+// filename is used to tell apart text formats without magic bytes.
+func ExtractBookMetadata(tempFile *os.File, filename string) (Metadata, error) {
+	extension, err := guessExtention(tempFile, filename)
 	if err != nil {
 		return Metadata{}, err
 	}
+	// KEPUB is EPUB for Kobo readers, it is told apart only by name
+	if extension == "epub" && strings.HasSuffix(strings.ToLower(filename), ".kepub.epub") {
+		extension = "kepub"
+	}
 	var m Metadata
 	switch extension {
 	case "pdf":
@@ -32,7 +56,7 @@ func ExtractBookMetadata(tempFile *os.File) (Metadata, error) {
 		if err != nil {
 			return Metadata{}, err
 		}
-	case "epub":
+	case "epub", "kepub":
 		m, err = getEpubMetadata(tempFile)
 		if err != nil {
 			return Metadata{}, err
@@ -42,18 +66,81 @@ func ExtractBookMetadata(tempFile *os.File) (Metadata, error) {
 		if err != nil {
 			return Metadata{}, err
 		}
+	case "fb2.zip":
+		m, err = getFb2ZipMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "mobi", "azw3":
+		m, err = getMobiMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "cbz":
+		m, err = getCbzMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "cbr":
+		m, err = getCbrMetadata(tempFile)
+		if errors.Is(err, errNotComic) {
+			// RAR archive without pages is not a book
+			return Metadata{}, nil
+		}
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "djvu":
+		m, err = getDjvuMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "txt", "md":
+		m, err = getTextMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "html":
+		m, err = getHtmlMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "rtf":
+		m, err = getRtfMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
+	case "docx":
+		m, err = getDocxMetadata(tempFile)
+		if err != nil {
+			return Metadata{}, err
+		}
 	}
 	m.Format = extension
 	return m, nil
 }
 
-func guessExtention(file *os.File) (string, error) {
+func guessExtention(file *os.File, filename string) (string, error) {
 	// TODO: move extensions to enum
 	data := make([]byte, 100*1024)
-	_, err := file.ReadAt(data, 0)
+	n, err := file.ReadAt(data, 0)
 	if err != nil && err != io.EOF {
 		return "", err
 	}
+	data = data[:n]
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if isMobi(data) {
+		return mobiFormat(data), nil
+	}
+	if isDjvu(data) {
+		return "djvu", nil
+	}
+	if strings.HasPrefix(string(data), rarMagic) {
+		return "cbr", nil
+	}
+	if strings.HasPrefix(string(data), rtfMagic) {
+		return "rtf", nil
+	}
 	mimeType := http.DetectContentType(data)
 	fmt.Println(mimeType)
 	switch mimeType {
@@ -62,12 +149,49 @@ func guessExtention(file *os.File) (string, error) {
 	case "application/epub+zip":
 		return "epub", nil
 	case "application/zip":
-		return "epub", nil
+		return zipFormat(file)
 	case "application/x-fictionbook+xml":
 		return "fb2", nil
 	case "text/xml; charset=utf-8":
+		if textFormat(ext) == "html" {
+			return "html", nil
+		}
 		return "fb2", nil
+	case "text/html; charset=utf-8":
+		return "html", nil
 	default:
+		if strings.HasPrefix(mimeType, "text/plain") {
+			return textFormat(ext), nil
+		}
 		return "", nil
 	}
 }
+
+// zipFormat tells books packed as ZIP apart by their content:
+// EPUB has mimetype or container entries, DOCX has word/document.xml,
+// .fb2.zip has a single FB2 file and comic book has mostly images.
+func zipFormat(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return "", nil
+	}
+	for _, f := range reader.File {
+		if f.Name == "mimetype" || f.Name == "META-INF/container.xml" {
+			return "epub", nil
+		}
+	}
+	if isDocx(reader) {
+		return "docx", nil
+	}
+	if isFb2Zip(reader) {
+		return "fb2.zip", nil
+	}
+	if isComicZip(reader) {
+		return "cbz", nil
+	}
+	return "", nil
+}
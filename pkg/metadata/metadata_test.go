@@ -1,15 +1,21 @@
 package metadata_test
 
 import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+
 	"github.com/vanadium23/kompanion/pkg/metadata"
 )
 
-const pathToTestDataFolder = "../../../test/test_data/books/"
+const pathToTestDataFolder = "../../test/test_data/books/"
 
 func readAll(path string) []byte {
 	file, err := os.Open(path)
@@ -21,6 +27,141 @@ func readAll(path string) []byte {
 	return b
 }
 
+func TestExtractBookMetadataUnknownText(t *testing.T) {
+	file, err := os.Open(pathToTestDataFolder + "Sample-TXT.txt")
+	require.NoError(t, err)
+	defer file.Close()
+
+	got, err := metadata.ExtractBookMetadata(file, "notes.log")
+	require.NoError(t, err)
+	require.Equal(t, "", got.Format)
+}
+
+func TestExtractBookMetadataKepub(t *testing.T) {
+	file, err := os.Open(pathToTestDataFolder + "Sample-EPUB3.epub")
+	require.NoError(t, err)
+	defer file.Close()
+
+	got, err := metadata.ExtractBookMetadata(file, "Sample.kepub.epub")
+	require.NoError(t, err)
+	require.Equal(t, "kepub", got.Format)
+	require.NotEmpty(t, got.Title)
+}
+
+// craftedMobi builds a minimal MOBI file with one record, whose EXTH header
+// claims 0xFFFFFFFF records but holds a title record and truncated garbage.
+func craftedMobi() []byte {
+	be := binary.BigEndian
+	header := make([]byte, 78+8)
+	copy(header[60:], "BOOKMOBI")
+	be.PutUint16(header[76:], 1)
+	be.PutUint32(header[78:], uint32(len(header)))
+
+	record0 := make([]byte, 132)
+	copy(record0[16:], "MOBI")
+	be.PutUint32(record0[20:], 116) // MOBI header length, EXTH follows it
+	be.PutUint32(record0[28:], 65001)
+	be.PutUint32(record0[108:], 0xFFFFFFFF)
+	be.PutUint32(record0[128:], 0x40)
+
+	exth := make([]byte, 12+8)
+	copy(exth, "EXTH")
+	be.PutUint32(exth[8:], 0xFFFFFFFF)
+	be.PutUint32(exth[12:], 503)
+	be.PutUint32(exth[16:], uint32(8+len("Crafted")))
+	exth = append(exth, "Crafted"...)
+	exth = append(exth, 0, 0, 0, 100, 0xFF, 0xFF) // truncated record
+
+	return append(append(header, record0...), exth...)
+}
+
+func TestExtractBookMetadataMalformedEXTH(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crafted.mobi")
+	require.NoError(t, os.WriteFile(path, craftedMobi(), 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	got, err := metadata.ExtractBookMetadata(file, "crafted.mobi")
+	require.NoError(t, err)
+	require.Equal(t, "Crafted", got.Title)
+	require.Equal(t, "mobi", got.Format)
+}
+
+func TestExtractBookMetadataBrokenEpubContainer(t *testing.T) {
+	containers := map[string]string{
+		"no rootfile": `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles></rootfiles></container>`,
+		"not xml":     `<container><rootfiles><rootfile full-path="content.opf"`,
+	}
+	for name, container := range containers {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "broken.epub")
+			archive, err := os.Create(path)
+			require.NoError(t, err)
+			w := zip.NewWriter(archive)
+			for entry, content := range map[string]string{"mimetype": "application/epub+zip", "META-INF/container.xml": container} {
+				f, err := w.Create(entry)
+				require.NoError(t, err)
+				_, err = f.Write([]byte(content))
+				require.NoError(t, err)
+			}
+			require.NoError(t, w.Close())
+			require.NoError(t, archive.Close())
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+			defer file.Close()
+			_, err = metadata.ExtractBookMetadata(file, "broken.epub")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestExtractBookMetadataFb2Encoding(t *testing.T) {
+	const fb2 = `<?xml version="1.0" encoding="windows-1251"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0"><description><title-info>
+<book-title>%s</book-title><annotation><p>%s</p></annotation>
+</title-info></description></FictionBook>`
+	cp1251 := func(s string) string {
+		b, _ := charmap.Windows1251.NewEncoder().String(s)
+		return b
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		title       string
+		description string
+	}{
+		{
+			name:        "windows-1251",
+			content:     fmt.Sprintf(fb2, cp1251("Трудно быть богом"), cp1251("Повесть — о земном историке.")),
+			title:       "Трудно быть богом",
+			description: "Повесть — о земном историке.",
+		},
+		{
+			name:        "UTF-8 declared as windows-1251",
+			content:     fmt.Sprintf(fb2, "Great Expectations", "Plans of others — and values."),
+			title:       "Great Expectations",
+			description: "Plans of others — and values.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "book.fb2")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+			file, err := os.Open(path)
+			require.NoError(t, err)
+			defer file.Close()
+
+			got, err := metadata.ExtractBookMetadata(file, "book.fb2")
+			require.NoError(t, err)
+			require.Equal(t, tt.title, got.Title)
+			require.Equal(t, tt.description, got.Description)
+		})
+	}
+}
+
 func TestExtractBookMetadata(t *testing.T) {
 
 	tests := []struct {
@@ -35,7 +176,10 @@ func TestExtractBookMetadata(t *testing.T) {
 			want: metadata.Metadata{
 				Title:  "A Princess of Mars",
 				Author: "Edgar Rice Burroughs",
+				Date:   "2016-01-04",
+				Pages:  252,
 				Format: "pdf",
+				Cover:  readAll(pathToTestDataFolder + "../covers/PrincessOfMars-PDF.jpg"),
 			},
 		},
 		{
@@ -49,17 +193,180 @@ func TestExtractBookMetadata(t *testing.T) {
 				ISBN:        "urn:uuid:12c6fed8-ec29-4343-ab36-9a48312ee01d",
 				Title:       "Crime and Punishment",
 				Description: "(From Wikipedia): Crime and Punishment (Russian: Преступлéние и наказáние, Prestupleniye i nakazaniye) is a novel by the Russian author Fyodor Dostoyevsky. It was first published in the literary journal The Russian Messenger in twelve monthly installments during 1866. It was later published in a single volume. It is the second of Dostoyevsky’s full-length novels following his return from ten years of exile in Siberia. Crime and Punishment is the first great novel of his “mature” period of writing. Crime and Punishment focuses on the mental anguish and moral dilemmas of Rodion Raskolnikov, an impoverished ex-student in St. Petersburg who formulates and executes a plan to kill an unscrupulous pawnbroker for her cash. Raskolnikov argues that with the pawnbroker’s money he can perform good deeds to counterbalance the crime, while ridding the world of a worthless vermin. He also commits this murder to test his own hypothesis that some people are naturally capable of such things, and even have the right to do them. Several times throughout the novel, Raskolnikov justifies his actions by comparing himself with Napoleon Bonaparte, believing that murder is permissible in pursuit of a higher purpose.",
+				Subjects:    []string{"Fiction", "Classics"},
 				Format:      "epub",
 				Cover:       readAll(pathToTestDataFolder + "../covers/CrimePunishment-EPUB2.jpg"),
 			},
 		},
+		{
+			name:     "EPUB3",
+			fileName: "Sample-EPUB3.epub",
+			want: metadata.Metadata{
+				ISBN:        "9780000000002",
+				Title:       "The Dispossessed",
+				Author:      "Ursula K. Le Guin",
+				Publisher:   "Harper & Row",
+				Date:        "1974-05-01",
+				Language:    "en",
+				Description: "An ambiguous utopia.",
+				Series:      "Hainish Cycle",
+				SeriesIndex: 5,
+				Subjects:    []string{"Science Fiction", "Utopias"},
+				Format:      "epub",
+			},
+		},
+		{
+			name:     "EPUB2 from Calibre",
+			fileName: "Sample-EPUB2-Calibre.epub",
+			want: metadata.Metadata{
+				ISBN:        "9780000000019",
+				Title:       "The Colour of Magic",
+				Author:      "Terry Pratchett",
+				Date:        "1983-11-24",
+				Language:    "en",
+				Series:      "Discworld",
+				SeriesIndex: 1,
+				Subjects:    []string{"Fantasy"},
+				Format:      "epub",
+			},
+		},
+		{
+			name:     "MOBI",
+			fileName: "PridePrejudice-MOBI.mobi",
+			want: metadata.Metadata{
+				Title:       "Pride and Prejudice",
+				Author:      "Jane Austen",
+				Publisher:   "BB eBooks Co., Ltd.",
+				Date:        "2016-01-06",
+				Language:    "en-gb",
+				Description: "Pride and Prejudice: In this historic romance, young Elizabeth Bennet strives for love, independence and honesty in the vapid high society of 19th century England.",
+				Format:      "mobi",
+				Cover:       readAll(pathToTestDataFolder + "../covers/PridePrejudice-MOBI.jpg"),
+			},
+		},
 		{
 			name:     "FB2",
 			fileName: "Great Expectations -- Charles Dickens.fb2",
 			want: metadata.Metadata{
-				Title:  "Great Expectations",
-				Format: "fb2",
-				Cover:  readAll(pathToTestDataFolder + "../covers/Great Expectations -- Charles Dickens.jpg"),
+				Title:       "Great Expectations",
+				Author:      "Charles Dickens",
+				Description: "Great Expectations chronicles the progress of Pip from childhood through adulthood. As he moves from the marshes of Kent to London society, he encounters a variety of extraordinary characters: from Magwitch, the escaped convict, to Miss Havisham and her ward, the arrogant and beautiful Estella. In this fascinating story, Dickens shows the dangers of being driven by a desire for wealth and social status. Pip must establish a sense of self against the plans which others seem to have for him — and somehow discover a firm set of values and priorities.",
+				Date:        "1860-1861",
+				Language:    "en",
+				Format:      "fb2",
+				Cover:       readAll(pathToTestDataFolder + "../covers/Great Expectations -- Charles Dickens.jpg"),
+			},
+		},
+		{
+			name:     "FB2 in ZIP",
+			fileName: "Sample-FB2.fb2.zip",
+			want: metadata.Metadata{
+				Title:       "Трудно быть богом",
+				Author:      "Аркадий Стругацкий, Борис Стругацкий",
+				Description: "Повесть о земном историке на чужой планете.",
+				Date:        "1964",
+				Publisher:   "Молодая гвардия",
+				ISBN:        "978-5-17-000000-0",
+				Language:    "ru",
+				Series:      "Мир Полудня",
+				SeriesIndex: 4,
+				Format:      "fb2.zip",
+				Cover:       readAll(pathToTestDataFolder + "../covers/Sample-FB2.jpg"),
+			},
+		},
+		{
+			name:     "CBZ",
+			fileName: "Comic-CBZ.cbz",
+			want: metadata.Metadata{
+				Title:       "The Long Night",
+				Author:      "Jane Doe",
+				Publisher:   "Kompanion Comics",
+				Date:        "2019",
+				Language:    "en",
+				Description: "Third issue of the series.",
+				Series:      "Night Watch",
+				SeriesIndex: 3,
+				Pages:       3,
+				Format:      "cbz",
+				Cover:       readAll(pathToTestDataFolder + "../covers/Comic-CBZ.jpg"),
+			},
+		},
+		{
+			name:     "CBR",
+			fileName: "Comic-CBR.cbr",
+			want: metadata.Metadata{
+				Pages:  2,
+				Format: "cbr",
+				Cover:  readAll(pathToTestDataFolder + "../covers/Comic-CBR.jpg"),
+			},
+		},
+		{
+			name:     "DJVU",
+			fileName: "Sample-DJVU.djvu",
+			want: metadata.Metadata{
+				Title:     "Sample Document",
+				Author:    "John Smith",
+				Publisher: "Kompanion Press",
+				Date:      "1999",
+				Pages:     1,
+				Format:    "djvu",
+				Cover:     readAll(pathToTestDataFolder + "../covers/Sample-DJVU.jpg"),
+			},
+		},
+		{
+			name:     "TXT",
+			fileName: "Sample-TXT.txt",
+			want: metadata.Metadata{
+				Title:    "The Time Machine",
+				Author:   "H. G. Wells",
+				Date:     "October 2, 2004",
+				Language: "English",
+				Format:   "txt",
+			},
+		},
+		{
+			name:     "MD",
+			fileName: "Sample-MD.md",
+			want: metadata.Metadata{
+				Title:  "Release notes",
+				Author: "Kompanion Team",
+				Date:   "2026-10-18",
+				Format: "md",
+			},
+		},
+		{
+			name:     "HTML",
+			fileName: "Sample-HTML.html",
+			want: metadata.Metadata{
+				Title:       "Reading on e-ink devices",
+				Author:      "Ann Writer",
+				Description: "Why e-ink is good for long articles.",
+				Publisher:   "Kompanion Blog",
+				Language:    "en",
+				Format:      "html",
+			},
+		},
+		{
+			name:     "RTF",
+			fileName: "Sample-RTF.rtf",
+			want: metadata.Metadata{
+				Title:       "Café Report",
+				Author:      "Pierre Dupont",
+				Description: "Quarterly notes",
+				Date:        "2021",
+				Format:      "rtf",
+			},
+		},
+		{
+			name:     "DOCX",
+			fileName: "Sample-DOCX.docx",
+			want: metadata.Metadata{
+				Title:       "Annual Report",
+				Author:      "Maria Garcia",
+				Description: "Results of the year",
+				Language:    "es-ES",
+				Date:        "2022-05-17",
+				Format:      "docx",
 			},
 		},
 	}
@@ -71,7 +378,7 @@ func TestExtractBookMetadata(t *testing.T) {
 			}
 			defer file.Close()
 
-			got, err := metadata.ExtractBookMetadata(file)
+			got, err := metadata.ExtractBookMetadata(file, tt.fileName)
 			if err != nil {
 				t.Fatalf("failed to get metadata: %s", err)
 			}
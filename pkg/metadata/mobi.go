@@ -0,0 +1,230 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/vanadium23/kompanion/pkg/utils"
+)
+
+// MOBI, AZW3 and KF8 books are PalmDB databases, where record 0 holds
+// PalmDOC and MOBI headers followed by EXTH header with metadata.
+// See https://wiki.mobileread.com/wiki/MOBI
+const (
+	palmDBHeaderLength = 78
+	palmDBTypeOffset   = 60
+	mobiMagic          = "BOOKMOBI"
+	mobiNoImage        = 0xFFFFFFFF
+	mobiEncodingUTF8   = 65001
+	exthFlagPresent    = 0x40
+)
+
+// EXTH record types
+const (
+	exthAuthor      = 100
+	exthPublisher   = 101
+	exthDescription = 103
+	exthISBN        = 104
+	exthPubDate     = 106
+	exthCoverOffset = 201
+	exthThumbOffset = 202
+	exthTitle       = 503
+	exthLanguage    = 524
+)
+
+var errNotMobi = errors.New("not a MOBI file")
+
+// isMobi checks PalmDB type and creator of the file header.
+func isMobi(header []byte) bool {
+	return len(header) >= palmDBTypeOffset+len(mobiMagic) &&
+		string(header[palmDBTypeOffset:palmDBTypeOffset+len(mobiMagic)]) == mobiMagic
+}
+
+// mobiFormat distinguishes KF8-only books (azw3) from MOBI ones,
+// including joint MOBI/KF8 files, by MOBI header version.
+func mobiFormat(header []byte) string {
+	if len(header) < palmDBHeaderLength+8 {
+		return "mobi"
+	}
+	offset := int(binary.BigEndian.Uint32(header[palmDBHeaderLength:]))
+	if len(header) < offset+40 || string(header[offset+16:offset+20]) != "MOBI" {
+		return "mobi"
+	}
+	if binary.BigEndian.Uint32(header[offset+36:]) >= 8 {
+		return "azw3"
+	}
+	return "mobi"
+}
+
+type palmDB struct {
+	file    io.ReaderAt
+	size    int64
+	offsets []uint32
+}
+
+func openPalmDB(file *os.File) (*palmDB, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, palmDBHeaderLength)
+	_, err = file.ReadAt(header, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !isMobi(header) {
+		return nil, errNotMobi
+	}
+
+	count := int(binary.BigEndian.Uint16(header[76:]))
+	table := make([]byte, count*8)
+	_, err = file.ReadAt(table, palmDBHeaderLength)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]uint32, count)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint32(table[i*8:])
+	}
+	return &palmDB{file: file, size: info.Size(), offsets: offsets}, nil
+}
+
+// record reads PalmDB record by index.
+func (db *palmDB) record(i int) ([]byte, error) {
+	if i < 0 || i >= len(db.offsets) {
+		return nil, fmt.Errorf("record %d out of range", i)
+	}
+	start, end := int64(db.offsets[i]), db.size
+	if i+1 < len(db.offsets) {
+		end = int64(db.offsets[i+1])
+	}
+	if start > end || end > db.size {
+		return nil, fmt.Errorf("record %d is corrupted", i)
+	}
+	data := make([]byte, end-start)
+	_, err := db.file.ReadAt(data, start)
+	return data, err
+}
+
+func getMobiMetadata(file *os.File) (Metadata, error) {
+	db, err := openPalmDB(file)
+	if err != nil {
+		return Metadata{}, err
+	}
+	record0, err := db.record(0)
+	if err != nil {
+		return Metadata{}, err
+	}
+	// PalmDOC header is 16 bytes, MOBI header follows it
+	if len(record0) < 132 || string(record0[16:20]) != "MOBI" {
+		return Metadata{}, errNotMobi
+	}
+	headerLength := int(binary.BigEndian.Uint32(record0[20:]))
+	encoding := binary.BigEndian.Uint32(record0[28:])
+	fullNameOffset := int(binary.BigEndian.Uint32(record0[84:]))
+	fullNameLength := int(binary.BigEndian.Uint32(record0[88:]))
+	locale := binary.BigEndian.Uint32(record0[92:])
+	firstImage := binary.BigEndian.Uint32(record0[108:])
+	exthFlags := binary.BigEndian.Uint32(record0[128:])
+
+	decode := func(b []byte) string {
+		if encoding == mobiEncodingUTF8 {
+			return strings.TrimSpace(string(b))
+		}
+		s, _ := charmap.Windows1252.NewDecoder().Bytes(b)
+		return strings.TrimSpace(string(s))
+	}
+
+	var m Metadata
+	if fullNameOffset+fullNameLength <= len(record0) {
+		m.Title = decode(record0[fullNameOffset : fullNameOffset+fullNameLength])
+	}
+
+	var authors []string
+	coverOffset, thumbOffset := uint32(mobiNoImage), uint32(mobiNoImage)
+	if exthFlags&exthFlagPresent != 0 {
+		for _, rec := range parseEXTH(record0[min(16+headerLength, len(record0)):]) {
+			switch rec.kind {
+			case exthAuthor:
+				authors = append(authors, decode(rec.data))
+			case exthPublisher:
+				m.Publisher = decode(rec.data)
+			case exthDescription:
+				m.Description = decode(rec.data)
+			case exthISBN:
+				m.ISBN = decode(rec.data)
+			case exthPubDate:
+				m.Date = decode(rec.data)
+			case exthTitle:
+				m.Title = decode(rec.data)
+			case exthLanguage:
+				m.Language = decode(rec.data)
+			case exthCoverOffset:
+				if len(rec.data) == 4 {
+					coverOffset = binary.BigEndian.Uint32(rec.data)
+				}
+			case exthThumbOffset:
+				if len(rec.data) == 4 {
+					thumbOffset = binary.BigEndian.Uint32(rec.data)
+				}
+			}
+		}
+	}
+	m.Author = strings.Join(authors, ", ")
+	if m.Language == "" {
+		m.Language = mobiLocale(locale)
+	}
+
+	if firstImage != mobiNoImage {
+		offset := utils.If(coverOffset != mobiNoImage, coverOffset, thumbOffset)
+		if offset != mobiNoImage {
+			cover, err := db.record(int(firstImage + offset))
+			if err == nil {
+				m.Cover = cover
+			}
+		}
+	}
+
+	return m, nil
+}
+
+type exthRecord struct {
+	kind uint32
+	data []byte
+}
+
+func parseEXTH(data []byte) []exthRecord {
+	if len(data) < 12 || string(data[:4]) != "EXTH" {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(data[8:]))
+	// count comes from the file, so records are not preallocated by it
+	var records []exthRecord
+	pos := 12
+	for i := 0; i < count && pos+8 <= len(data); i++ {
+		kind := binary.BigEndian.Uint32(data[pos:])
+		length := int(binary.BigEndian.Uint32(data[pos+4:]))
+		if length < 8 || pos+length > len(data) {
+			break
+		}
+		records = append(records, exthRecord{kind: kind, data: data[pos+8 : pos+length]})
+		pos += length
+	}
+	return records
+}
+
+// mobiLocale converts MOBI locale (Windows LCID, language is in the low byte)
+// to language code, only common languages are known.
+func mobiLocale(locale uint32) string {
+	languages := map[uint32]string{
+		0x04: "zh", 0x07: "de", 0x09: "en", 0x0a: "es", 0x0c: "fr", 0x10: "it",
+		0x11: "ja", 0x13: "nl", 0x15: "pl", 0x16: "pt", 0x19: "ru", 0x22: "uk",
+	}
+	return languages[locale&0xFF]
+}
@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Open Library search returns works with editions data merged,
+// description is only available in the work itself.
+// See https://openlibrary.org/dev/docs/api/search
+const (
+	OpenLibraryURL       = "https://openlibrary.org"
+	OpenLibraryCoversURL = "https://covers.openlibrary.org"
+
+	openLibraryFields   = "key,title,subtitle,author_name,first_publish_year,publisher,isbn,language,subject,cover_i,number_of_pages_median"
+	openLibrarySubjects = 10
+)
+
+type openLibrarySearch struct {
+	Docs []struct {
+		Key              string   `json:"key"`
+		Title            string   `json:"title"`
+		Subtitle         string   `json:"subtitle"`
+		AuthorName       []string `json:"author_name"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		Publisher        []string `json:"publisher"`
+		ISBN             []string `json:"isbn"`
+		Language         []string `json:"language"`
+		Subject          []string `json:"subject"`
+		CoverID          int      `json:"cover_i"`
+		Pages            int      `json:"number_of_pages_median"`
+	} `json:"docs"`
+}
+
+type openLibraryWork struct {
+	// description is either a string or {"type": "/type/text", "value": "..."}
+	Description json.RawMessage `json:"description"`
+}
+
+// OpenLibrary looks up books in Open Library catalog.
+type OpenLibrary struct {
+	baseURL   string
+	coversURL string
+	client    *http.Client
+}
+
+// NewOpenLibrary creates provider, empty URLs are replaced with public ones.
+func NewOpenLibrary(baseURL, coversURL string, client *http.Client) *OpenLibrary {
+	if baseURL == "" {
+		baseURL = OpenLibraryURL
+	}
+	if coversURL == "" {
+		coversURL = OpenLibraryCoversURL
+	}
+	return &OpenLibrary{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		coversURL: strings.TrimSuffix(coversURL, "/"),
+		client:    defaultClient(client),
+	}
+}
+
+func (p *OpenLibrary) Name() string {
+	return "openlibrary"
+}
+
+func (p *OpenLibrary) Lookup(ctx context.Context, query Query) (Metadata, error) {
+	params := url.Values{}
+	if query.ISBN != "" {
+		params.Set("isbn", normalizeISBN(query.ISBN))
+	} else {
+		params.Set("title", query.Title)
+		if query.Author != "" {
+			params.Set("author", query.Author)
+		}
+	}
+	params.Set("fields", openLibraryFields)
+	params.Set("limit", "1")
+
+	var search openLibrarySearch
+	err := getJSON(ctx, p.client, p.baseURL+"/search.json?"+params.Encode(), &search)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("OpenLibrary - Lookup - search: %w", err)
+	}
+	if len(search.Docs) == 0 {
+		return Metadata{}, ErrNotFound
+	}
+	doc := search.Docs[0]
+
+	m := Metadata{
+		Title:  strings.TrimSpace(doc.Title),
+		Author: joinTrimmed(doc.AuthorName, ", "),
+		ISBN:   pickISBN(query.ISBN, doc.ISBN),
+		Pages:  doc.Pages,
+	}
+	if doc.Subtitle != "" {
+		m.Title += ": " + strings.TrimSpace(doc.Subtitle)
+	}
+	if doc.FirstPublishYear > 0 {
+		m.Date = strconv.Itoa(doc.FirstPublishYear)
+	}
+	if len(doc.Publisher) > 0 {
+		m.Publisher = strings.TrimSpace(doc.Publisher[0])
+	}
+	if len(doc.Language) > 0 {
+		m.Language = languageCode(doc.Language[0])
+	}
+	m.Subjects = doc.Subject[:min(len(doc.Subject), openLibrarySubjects)]
+
+	if doc.Key != "" {
+		var work openLibraryWork
+		err = getJSON(ctx, p.client, p.baseURL+doc.Key+".json", &work)
+		if err == nil {
+			m.Description = openLibraryText(work.Description)
+		}
+	}
+	if doc.CoverID > 0 {
+		m.Cover, err = getCover(ctx, p.client, fmt.Sprintf("%s/b/id/%d-L.jpg?default=false", p.coversURL, doc.CoverID))
+		if err != nil {
+			return Metadata{}, fmt.Errorf("OpenLibrary - Lookup - cover: %w", err)
+		}
+	}
+	return m, nil
+}
+
+func openLibraryText(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text)
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &typed) == nil {
+		return strings.TrimSpace(typed.Value)
+	}
+	return ""
+}
+
+// pickISBN keeps ISBN of the query, otherwise picks ISBN-13 if any.
+func pickISBN(queried string, isbns []string) string {
+	if queried != "" {
+		return queried
+	}
+	for _, isbn := range isbns {
+		if len(isbn) == 13 {
+			return isbn
+		}
+	}
+	if len(isbns) > 0 {
+		return isbns[0]
+	}
+	return ""
+}
+
+// normalizeISBN removes hyphens and spaces, catalogs search by digits only.
+func normalizeISBN(isbn string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimPrefix(strings.ToUpper(isbn), "URN:ISBN:"))
+}
+
+// languageCode converts ISO 639-2 codes, e.g. "eng", to two letter ones.
+func languageCode(code string) string {
+	base, err := language.ParseBase(strings.TrimSpace(code))
+	if err != nil {
+		return code
+	}
+	return base.String()
+}
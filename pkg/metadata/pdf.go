@@ -2,8 +2,19 @@ package metadata
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
 	"os"
 	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	_ "golang.org/x/image/tiff"
 )
 
 // PDFMetadata holds the extracted PDFmetadata information
@@ -14,8 +25,196 @@ type PDFMetadata struct {
 	Keywords string
 }
 
-// extractPDFMetadataFromHeader scans the first part of the PDF file for PDFmetadata information
+// xmpMeta is a subset of Dublin Core and XMP basic schemas of XMP packet.
+type xmpMeta struct {
+	Descriptions []struct {
+		Title          []string `xml:"title>Alt>li"`
+		Creator        []string `xml:"creator>Seq>li"`
+		Description    []string `xml:"description>Alt>li"`
+		Publisher      []string `xml:"publisher>Bag>li"`
+		Language       []string `xml:"language>Bag>li"`
+		Subject        []string `xml:"subject>Bag>li"`
+		Date           []string `xml:"date>Seq>li"`
+		CreateDate     string   `xml:"CreateDate"`
+		CreateDateAttr string   `xml:"CreateDate,attr"`
+	} `xml:"Description"`
+}
+
+// extractPdfMetadata reads XMP and Info dictionary, page count and cover
+// from the first page. Files pdfcpu can't read are scanned for Info entries.
 func extractPdfMetadata(tmpFile *os.File) (Metadata, error) {
+	api.DisableConfigDir()
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXTRACTIMAGES
+	conf.ValidationMode = model.ValidationRelaxed
+
+	ctx, err := api.ReadValidateAndOptimize(tmpFile, conf)
+	if err != nil {
+		_, err = tmpFile.Seek(0, io.SeekStart)
+		if err != nil {
+			return Metadata{}, err
+		}
+		return scanPdfMetadata(tmpFile)
+	}
+
+	m := pdfInfo(ctx)
+	xmp := pdfXMP(ctx)
+	m.Title = firstNonEmpty(xmp.Title, m.Title)
+	m.Author = firstNonEmpty(xmp.Author, m.Author)
+	m.Description = firstNonEmpty(xmp.Description, m.Description)
+	m.Publisher = firstNonEmpty(xmp.Publisher, m.Publisher)
+	m.Language = firstNonEmpty(xmp.Language, m.Language)
+	m.Date = firstNonEmpty(xmp.Date, m.Date)
+	if len(xmp.Subjects) > 0 {
+		m.Subjects = xmp.Subjects
+	}
+	m.Pages = ctx.PageCount
+	m.Cover = pdfCover(ctx)
+	return m, nil
+}
+
+// pdfInfo reads document information dictionary.
+func pdfInfo(ctx *model.Context) Metadata {
+	var m Metadata
+	if ctx.Info == nil {
+		return m
+	}
+	info, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || info == nil {
+		return m
+	}
+	text := func(key string) string {
+		value, ok := info[key]
+		if !ok {
+			return ""
+		}
+		s, err := ctx.DereferenceText(value)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(s)
+	}
+	m.Title = text("Title")
+	m.Author = text("Author")
+	m.Description = text("Subject")
+	if lang, err := ctx.DereferenceText(ctx.RootDict["Lang"]); err == nil {
+		m.Language = strings.TrimSpace(lang)
+	}
+	// dates are D:YYYYMMDDHHmmSS, only the date is kept
+	if date := strings.TrimPrefix(text("CreationDate"), "D:"); len(date) >= 8 {
+		m.Date = date[:4] + "-" + date[4:6] + "-" + date[6:8]
+	} else if len(date) >= 4 {
+		m.Date = date[:4]
+	}
+	return m
+}
+
+// pdfXMP reads XMP packet of the document catalog.
+func pdfXMP(ctx *model.Context) Metadata {
+	var m Metadata
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return m
+	}
+	sd, _, err := ctx.DereferenceStreamDict(catalog["Metadata"])
+	if err != nil || sd == nil {
+		return m
+	}
+	if err = sd.Decode(); err != nil {
+		return m
+	}
+	return parseXMP(sd.Content)
+}
+
+func parseXMP(data []byte) Metadata {
+	var m Metadata
+	// XMP packet is rdf:RDF, optionally wrapped into x:xmpmeta
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return m
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "RDF" {
+			continue
+		}
+		var meta xmpMeta
+		if decoder.DecodeElement(&meta, &start) != nil {
+			return m
+		}
+		for _, d := range meta.Descriptions {
+			m.Title = firstNonEmpty(m.Title, joinTrimmed(d.Title, " "))
+			m.Author = firstNonEmpty(m.Author, joinTrimmed(d.Creator, ", "))
+			m.Description = firstNonEmpty(m.Description, joinTrimmed(d.Description, " "))
+			m.Publisher = firstNonEmpty(m.Publisher, joinTrimmed(d.Publisher, ", "))
+			if len(d.Language) > 0 {
+				m.Language = firstNonEmpty(m.Language, strings.TrimSpace(d.Language[0]))
+			}
+			if len(d.Date) > 0 {
+				m.Date = firstNonEmpty(m.Date, strings.TrimSpace(d.Date[0]))
+			}
+			m.Date = firstNonEmpty(m.Date, strings.TrimSpace(d.CreateDate), strings.TrimSpace(d.CreateDateAttr))
+			for _, subject := range d.Subject {
+				if subject = strings.TrimSpace(subject); subject != "" {
+					m.Subjects = append(m.Subjects, subject)
+				}
+			}
+		}
+		// XMP dates are ISO 8601 timestamps
+		if len(m.Date) > len("2006-01-02") {
+			m.Date = m.Date[:len("2006-01-02")]
+		}
+		return m
+	}
+}
+
+// pdfCover picks the largest image of the first page and converts it to JPEG.
+// Pages drawn with vector graphics or text only have no cover.
+func pdfCover(ctx *model.Context) []byte {
+	var cover *model.ImageObject
+	coverObjNr, coverArea := 0, 0
+	for _, objNr := range pdfcpu.ImageObjNrs(ctx, 1) {
+		imageObj := ctx.Optimize.ImageObjects[objNr]
+		if imageObj == nil || imageObj.ImageDict == nil {
+			continue
+		}
+		width := imageObj.ImageDict.IntEntry("Width")
+		height := imageObj.ImageDict.IntEntry("Height")
+		if width == nil || height == nil || *width**height <= coverArea {
+			continue
+		}
+		cover, coverObjNr, coverArea = imageObj, objNr, *width**height
+	}
+	if cover == nil {
+		return nil
+	}
+
+	img, err := pdfcpu.ExtractImage(ctx, cover.ImageDict, false, cover.ResourceNames[0], coverObjNr, false)
+	if err != nil || img == nil {
+		return nil
+	}
+	data, err := io.ReadAll(img)
+	if err != nil {
+		return nil
+	}
+	if img.FileType == "jpg" {
+		return data
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 90})
+	if err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// scanPdfMetadata scans lines of the PDF file for Info dictionary entries
+func scanPdfMetadata(tmpFile *os.File) (Metadata, error) {
 	scanner := bufio.NewScanner(tmpFile)
 	var PDFmetadata Metadata
 	for scanner.Scan() {
@@ -27,12 +226,6 @@ func extractPdfMetadata(tmpFile *os.File) (Metadata, error) {
 		if strings.Contains(line, "/Author") {
 			PDFmetadata.Author = extractValue(line, "/Author")
 		}
-		// if strings.Contains(line, "/Subject") {
-		// 	PDFmetadata.Subject = extractValue(line, "/Subject")
-		// }
-		// if strings.Contains(line, "/Keywords") {
-		// 	PDFmetadata.Keywords = extractValue(line, "/Keywords")
-		// }
 
 		// Break early if we've found all fields
 		if PDFmetadata.Title != "" && PDFmetadata.Author != "" {
@@ -60,3 +253,22 @@ func extractValue(line string, field string) string {
 	}
 	return line[start : start+end]
 }
+
+func joinTrimmed(values []string, sep string) string {
+	var trimmed []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return strings.Join(trimmed, sep)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
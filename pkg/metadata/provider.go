@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	providerTimeout = 10 * time.Second
+	// covers larger than this are not downloaded
+	providerCoverLimit = 10 * 1024 * 1024
+)
+
+// ErrNotFound is returned by provider, when catalog has no matching book.
+var ErrNotFound = errors.New("book not found")
+
+// Query describes a book to look up, ISBN is preferred over title and author.
+type Query struct {
+	ISBN   string
+	Title  string
+	Author string
+}
+
+// Provider looks up metadata and cover of a book in online catalog.
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, query Query) (Metadata, error)
+}
+
+func defaultClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: providerTimeout}
+}
+
+func get(ctx context.Context, client *http.Client, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, redactURLError(err, rawURL)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, redactURLError(err, rawURL)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %s", redactURL(rawURL), resp.Status)
+	}
+	return resp, nil
+}
+
+// redactURL drops query of the url, as it may carry API key.
+func redactURL(rawURL string) string {
+	if i := strings.IndexByte(rawURL, '?'); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
+}
+
+// redactURLError drops query from url of *url.Error,
+// other errors may contain the url too, so they are replaced.
+func redactURLError(err error, rawURL string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		redacted := *urlErr
+		redacted.URL = redactURL(urlErr.URL)
+		return &redacted
+	}
+	if strings.Contains(err.Error(), rawURL) {
+		return fmt.Errorf("GET %s: invalid request", redactURL(rawURL))
+	}
+	return err
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// getCover downloads cover image, missing cover is not an error.
+func getCover(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	resp, err := get(ctx, client, url)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	cover, err := io.ReadAll(io.LimitReader(resp.Body, providerCoverLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(cover) > providerCoverLimit {
+		return nil, fmt.Errorf("GET %s: cover is larger than %d bytes", redactURL(url), providerCoverLimit)
+	}
+	return cover, nil
+}
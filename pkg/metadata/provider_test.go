@@ -0,0 +1,164 @@
+package metadata_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/pkg/metadata"
+)
+
+var stubCover = []byte("\xff\xd8\xff\xe0cover")
+
+func TestOpenLibraryLookup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search.json", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("isbn") == "9780141439600":
+			w.Write([]byte(`{"docs": [{
+				"key": "/works/OL1W",
+				"title": "A Tale of Two Cities",
+				"author_name": ["Charles Dickens"],
+				"first_publish_year": 1859,
+				"publisher": ["Penguin"],
+				"isbn": ["0141439602", "9780141439600"],
+				"language": ["eng"],
+				"subject": ["Fiction", "French Revolution"],
+				"cover_i": 42,
+				"number_of_pages_median": 489
+			}]}`))
+		case r.URL.Query().Get("title") == "Unknown":
+			w.Write([]byte(`{"docs": []}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	mux.HandleFunc("/works/OL1W.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"description": {"type": "/type/text", "value": "It was the best of times."}}`))
+	})
+	mux.HandleFunc("/b/id/42-L.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(stubCover)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := metadata.NewOpenLibrary(server.URL, server.URL, server.Client())
+
+	m, err := provider.Lookup(context.Background(), metadata.Query{ISBN: "978-0-14-143960-0"})
+	require.NoError(t, err)
+	assert.Equal(t, metadata.Metadata{
+		ISBN:        "978-0-14-143960-0",
+		Title:       "A Tale of Two Cities",
+		Author:      "Charles Dickens",
+		Description: "It was the best of times.",
+		Date:        "1859",
+		Publisher:   "Penguin",
+		Language:    "en",
+		Subjects:    []string{"Fiction", "French Revolution"},
+		Pages:       489,
+		Cover:       stubCover,
+	}, m)
+
+	_, err = provider.Lookup(context.Background(), metadata.Query{Title: "Unknown"})
+	assert.ErrorIs(t, err, metadata.ErrNotFound)
+}
+
+func TestGoogleBooksLookup(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books/v1/volumes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case `intitle:"Dune" inauthor:"Frank Herbert"`:
+			w.Write([]byte(`{"items": [{"volumeInfo": {
+				"title": "Dune",
+				"authors": ["Frank Herbert"],
+				"publisher": "Ace",
+				"publishedDate": "1990-09-01",
+				"description": "Desert planet.",
+				"industryIdentifiers": [
+					{"type": "ISBN_10", "identifier": "0441172717"},
+					{"type": "ISBN_13", "identifier": "9780441172719"}
+				],
+				"pageCount": 535,
+				"categories": ["Fiction"],
+				"language": "en",
+				"imageLinks": {"thumbnail": "` + server.URL + `/cover?id=1&edge=curl"}
+			}}]}`))
+		default:
+			w.Write([]byte(`{"totalItems": 0}`))
+		}
+	})
+	mux.HandleFunc("/cover", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("edge") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(stubCover)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := metadata.NewGoogleBooks(server.URL, "", server.Client())
+
+	m, err := provider.Lookup(context.Background(), metadata.Query{Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	assert.Equal(t, metadata.Metadata{
+		ISBN:        "9780441172719",
+		Title:       "Dune",
+		Author:      "Frank Herbert",
+		Description: "Desert planet.",
+		Date:        "1990-09-01",
+		Publisher:   "Ace",
+		Language:    "en",
+		Subjects:    []string{"Fiction"},
+		Pages:       535,
+		Cover:       stubCover,
+	}, m)
+
+	_, err = provider.Lookup(context.Background(), metadata.Query{ISBN: "0000000000"})
+	assert.ErrorIs(t, err, metadata.ErrNotFound)
+}
+
+func TestGoogleBooksErrorsHideKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	provider := metadata.NewGoogleBooks(server.URL, "secret-key", server.Client())
+
+	_, err := provider.Lookup(context.Background(), metadata.Query{Title: "Dune"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.NotContains(t, err.Error(), "secret-key")
+
+	// transport errors carry url too
+	server.Close()
+	_, err = provider.Lookup(context.Background(), metadata.Query{Title: "Dune"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-key")
+}
+
+func TestOpenLibraryRejectsLargeCover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"docs": [{"key": "/works/OL1W", "title": "Huge", "cover_i": 42}]}`))
+	})
+	mux.HandleFunc("/works/OL1W.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/b/id/42-L.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte{0xff}, 10*1024*1024+1))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := metadata.NewOpenLibrary(server.URL, server.URL, server.Client())
+
+	_, err := provider.Lookup(context.Background(), metadata.Query{Title: "Huge"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cover is larger than")
+}
@@ -0,0 +1,217 @@
+// Package placeholder draws covers for books without one:
+// title and author on a background, which colour is derived from the book.
+package placeholder
+
+import (
+	"bytes"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Size of generated cover, the same aspect ratio as most e-reader screens.
+const (
+	Width  = 600
+	Height = 800
+)
+
+const (
+	margin         = 60
+	border         = 16
+	maxTitleLines  = 6
+	maxAuthorLines = 2
+	authorSize     = 28
+	quality        = 90
+	ellipsis       = "…"
+)
+
+// title is drawn with the largest size, which fits into maxTitleLines
+var titleSizes = []float64{56, 48, 40, 34}
+
+var (
+	titleFont  = mustParse(gobold.TTF)
+	authorFont = mustParse(goregular.TTF)
+)
+
+func mustParse(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Generate draws JPEG cover, seed (e.g. book id) picks background colour,
+// so the same book always gets the same cover.
+func Generate(title, author, seed string) ([]byte, error) {
+	background := Color(seed)
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	// lighter frame inside the cover
+	frame := image.Rect(border, border, Width-border, Height-border)
+	light := image.NewUniform(color.RGBA{255, 255, 255, 96})
+	for _, r := range []image.Rectangle{
+		{frame.Min, image.Pt(frame.Max.X, frame.Min.Y+2)},
+		{image.Pt(frame.Min.X, frame.Max.Y-2), frame.Max},
+		{frame.Min, image.Pt(frame.Min.X+2, frame.Max.Y)},
+		{image.Pt(frame.Max.X-2, frame.Min.Y), frame.Max},
+	} {
+		draw.Draw(img, r, light, image.Point{}, draw.Over)
+	}
+
+	textWidth := fixed.I(Width - 2*margin)
+	titleFace, titleLines, err := fitTitle(strings.TrimSpace(title), textWidth)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	drawLines(img, titleFace, titleLines, Height/4)
+
+	authorFace, err := opentype.NewFace(authorFont, &opentype.FaceOptions{Size: authorSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer authorFace.Close()
+	authorLines := wrap(authorFace, strings.TrimSpace(author), textWidth, maxAuthorLines)
+	lineHeight := authorFace.Metrics().Height.Ceil()
+	drawLines(img, authorFace, authorLines, Height-margin-lineHeight*len(authorLines))
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Color derives muted background colour from seed.
+func Color(seed string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	hue := float64(h.Sum32()%360) / 360
+	return hslToRGB(hue, 0.45, 0.4)
+}
+
+func fitTitle(title string, width fixed.Int26_6) (font.Face, []string, error) {
+	for i, size := range titleSizes {
+		face, err := opentype.NewFace(titleFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := wrap(face, title, width, math.MaxInt)
+		if len(lines) <= maxTitleLines || i == len(titleSizes)-1 {
+			return face, truncateLines(face, lines, width, maxTitleLines), nil
+		}
+		face.Close()
+	}
+	return nil, nil, nil
+}
+
+// wrap splits text into lines not wider than width, too long words are broken.
+func wrap(face font.Face, text string, width fixed.Int26_6, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if font.MeasureString(face, candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		// break word, which does not fit into a line by itself
+		for font.MeasureString(face, word) > width {
+			n := fitRunes(face, word, width)
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return truncateLines(face, lines, width, maxLines)
+}
+
+// truncateLines keeps maxLines and marks cut text with ellipsis.
+func truncateLines(face font.Face, lines []string, width fixed.Int26_6, maxLines int) []string {
+	if len(lines) <= maxLines {
+		return lines
+	}
+	lines = lines[:maxLines]
+	last := lines[maxLines-1]
+	for last != "" && font.MeasureString(face, last+ellipsis) > width {
+		_, size := utf8.DecodeLastRuneInString(last)
+		last = last[:len(last)-size]
+	}
+	lines[maxLines-1] = strings.TrimSpace(last) + ellipsis
+	return lines
+}
+
+// fitRunes returns byte length of the longest prefix not wider than width,
+// at least one rune is returned.
+func fitRunes(face font.Face, word string, width fixed.Int26_6) int {
+	n := 0
+	for i, r := range word {
+		if i > 0 && font.MeasureString(face, word[:i+utf8.RuneLen(r)]) > width {
+			break
+		}
+		n = i + utf8.RuneLen(r)
+	}
+	return n
+}
+
+// drawLines draws centered lines starting from top.
+func drawLines(img draw.Image, face font.Face, lines []string, top int) {
+	metrics := face.Metrics()
+	d := font.Drawer{Dst: img, Src: image.White, Face: face}
+	y := fixed.I(top) + metrics.Ascent
+	for _, line := range lines {
+		d.Dot = fixed.Point26_6{
+			X: (fixed.I(Width) - d.MeasureString(line)) / 2,
+			Y: y,
+		}
+		d.DrawString(line)
+		y += metrics.Height
+	}
+}
+
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h*6, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch int(h * 6) {
+	case 0:
+		r, g, b = c, x, 0
+	case 1:
+		r, g, b = x, c, 0
+	case 2:
+		r, g, b = 0, c, x
+	case 3:
+		r, g, b = 0, x, c
+	case 4:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 255,
+	}
+}
@@ -0,0 +1,49 @@
+package placeholder_test
+
+import (
+	"bytes"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vanadium23/kompanion/pkg/placeholder"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		author string
+	}{
+		{"short", "Dune", "Frank Herbert"},
+		{"markup", `<script>alert("x")</script> & Co`, "Tom & Jerry"},
+		{"cyrillic", "Преступление и наказание", "Фёдор Достоевский"},
+		{"long", strings.Repeat("Very long title of the book ", 20), strings.Repeat("Author ", 30)},
+		{"long word", strings.Repeat("a", 200), ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cover, err := placeholder.Generate(tt.title, tt.author, "book-id")
+			require.NoError(t, err)
+			img, err := jpeg.Decode(bytes.NewReader(cover))
+			require.NoError(t, err)
+			assert.Equal(t, placeholder.Width, img.Bounds().Dx())
+			assert.Equal(t, placeholder.Height, img.Bounds().Dy())
+		})
+	}
+}
+
+func TestGenerateIsStable(t *testing.T) {
+	first, err := placeholder.Generate("Dune", "Frank Herbert", "book-1")
+	require.NoError(t, err)
+	second, err := placeholder.Generate("Dune", "Frank Herbert", "book-1")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	assert.Equal(t, placeholder.Color("book-1"), placeholder.Color("book-1"))
+	assert.NotEqual(t, placeholder.Color("book-1"), placeholder.Color("book-2"))
+}